@@ -13,7 +13,7 @@ import (
 )
 
 func main() {
-	activation := flag.String("activation", "relu", "activation function to use (must be 'sigmoid|tanh|relu')")
+	activation := flag.String("activation", "relu", fmt.Sprintf("activation function to use (must be '%s')", strings.Join(qndnn.Activations(), "|")))
 	layers := flag.String("layers", "", "csv value for layer sizes (e.g. input=4,hidden1=4,hidden2=4,output=2 == '4, 4, 4, 1'")
 	name := flag.String("name", "mynet.qndnn", "name of the net, to be used as filename")
 	flag.Parse()
@@ -30,13 +30,11 @@ func main() {
 		layerSizes = append(layerSizes, int(ls))
 	}
 
-	slog.Info("creating net", "layer configuration", layerSizes, "activation", activation)
-	f := qndnn.WithSigmoid()
-	switch *activation {
-	case "tanh":
-		f = qndnn.WithTanh()
-	case "relu":
-		f = qndnn.WithRelu()
+	slog.Info("creating net", "layer configuration", layerSizes, "activation", *activation)
+	f, err := qndnn.WithActivation(*activation)
+	if err != nil {
+		slog.Error("can't resolve activation", "err", err)
+		os.Exit(1)
 	}
 
 	nn := qndnn.NewNeuralNet(f, layerSizes...)
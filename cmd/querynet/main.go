@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"flag"
+	"fmt"
 	"log/slog"
 	"os"
 	"strconv"
@@ -12,7 +13,7 @@ import (
 )
 
 func main() {
-	activation := flag.String("activation", "relu", "activation function to use (must be 'sigmoid|tanh|relu')")
+	activation := flag.String("activation", "", fmt.Sprintf("activation function to use (must be '%s'); stored activation if empty, relu for files stored without one", strings.Join(qndnn.Activations(), "|")))
 	file := flag.String("file", "./mynet.qndnn", "file path to the stored qndnn file")
 	input := flag.String("input", "", "input in csv form")
 	flag.Parse()
@@ -34,16 +35,19 @@ func main() {
 		in = append(in, pv)
 	}
 
-	f := qndnn.WithSigmoid()
-	switch *activation {
-	case "tanh":
-		f = qndnn.WithTanh()
-	case "relu":
-		f = qndnn.WithRelu()
+	f := qndnn.WithRelu() // default of mknet, for files stored without activation
+	opts := []qndnn.NetOption{qndnn.WithFallbackActivation()}
+	if *activation != "" {
+		f, err = qndnn.WithActivation(*activation)
+		if err != nil {
+			slog.Error("can't resolve activation", "err", err)
+			os.Exit(1)
+		}
+		opts = nil // stored activations must match
 	}
 
 	buf := bytes.NewBuffer(content)
-	nn, err := qndnn.NewNeuralNetFromSerialized(f, buf.String())
+	nn, err := qndnn.NewNeuralNetFromSerialized(f, buf.String(), opts...)
 	if err != nil {
 		slog.Error("couldn't read network", "err", err)
 		os.Exit(1)
@@ -2,9 +2,13 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
 
@@ -12,7 +16,7 @@ import (
 )
 
 func main() {
-	activation := flag.String("activation", "relu", "activation function to use (must be 'sigmoid|tanh|relu')")
+	activation := flag.String("activation", "", fmt.Sprintf("activation function to use (must be '%s'); stored activation if empty, relu for files stored without one", strings.Join(qndnn.Activations(), "|")))
 	file := flag.String("file", "./mynet.qndnn", "file path to the stored qndnn file")
 	input := flag.String("input", "", "input in csv form")
 	output := flag.String("expected", "", "expected output in csv form")
@@ -48,28 +52,37 @@ func main() {
 		out = append(out, pv)
 	}
 
-	f := qndnn.WithSigmoid()
-	switch *activation {
-	case "tanh":
-		f = qndnn.WithTanh()
-	case "relu":
-		f = qndnn.WithRelu()
+	f := qndnn.WithRelu() // default of mknet, for files stored without activation
+	opts := []qndnn.NetOption{qndnn.WithFallbackActivation()}
+	if *activation != "" {
+		f, err = qndnn.WithActivation(*activation)
+		if err != nil {
+			slog.Error("can't resolve activation", "err", err)
+			os.Exit(1)
+		}
+		opts = nil // stored activations must match
 	}
 
 	buf := bytes.NewBuffer(content)
-	nn, err := qndnn.NewNeuralNetFromSerialized(f, buf.String())
+	nn, err := qndnn.NewNeuralNetFromSerialized(f, buf.String(), opts...)
 	if err != nil {
 		slog.Error("couldn't read network", "err", err)
 		os.Exit(1)
 	}
 
-	err = nn.Train([]qndnn.Expectations{
+	// on interrupt, training stops and the network trained so far is written
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	_, err = nn.TrainContext(ctx, []qndnn.Expectations{
 		{
 			Input:  in,
 			Output: out,
 		},
 	}, *learningRate, qndnn.RoundStrategy(*rounds))
-	if err != nil {
+	if errors.Is(err, context.Canceled) {
+		slog.Warn("training interrupted")
+	} else if err != nil {
 		slog.Error("couldn't train network", "err", err)
 		os.Exit(1)
 	}
@@ -15,11 +15,11 @@ func main() {
 	fmt.Println(out)
 	// example out: [0.8436008352145469]
 
-	_ = nn.Train( // returns error if input or output is wrong dimension
+	_, _ = nn.Train( // returns error if input or output is wrong dimension
 		[]qndnn.Expectations{
 			{
-				[]float64{1, 2, 3}, // on input ...
-				[]float64{.42},     // ... expected output
+				Input:  []float64{1, 2, 3}, // on input ...
+				Output: []float64{.42},     // ... expected output
 			},
 		},
 		.4,                         // learning rate eta
@@ -19,16 +19,16 @@ func main() {
 	// demo out: [0.8655444560780231]
 
 	start := time.Now()
-	slog.Info("start", start.Format(time.DateTime))
-	_ = nn.Train( // returns error if input or output is wrong dimension
+	slog.Info("start", "at", start.Format(time.DateTime))
+	_, _ = nn.Train( // returns error if input or output is wrong dimension
 		[]qndnn.Expectations{
 			{
-				[]float64{1, 2, 3}, // on input ...
-				[]float64{.42},     // ... expected output
+				Input:  []float64{1, 2, 3}, // on input ...
+				Output: []float64{.42},     // ... expected output
 			},
 			{
-				[]float64{5, 2, 3}, // on input ...
-				[]float64{.56},     // ... expected output
+				Input:  []float64{5, 2, 3}, // on input ...
+				Output: []float64{.56},     // ... expected output
 			},
 		},
 		.25, // learning rate eta
@@ -0,0 +1,220 @@
+package qndnn
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+)
+
+// benchmarkShapes range from a tiny net to a few thousand weights
+var benchmarkShapes = [][]int{
+	{2, 3, 1},
+	{4, 8, 8, 1},
+	{16, 32, 32, 4},
+	{32, 64, 32, 8},
+}
+
+var benchmarkActivations = map[string]*func(*Neuron) *Neuron{
+	"sigmoid": WithSigmoid(),
+	"relu":    WithRelu(),
+	"tanh":    WithTanh(),
+}
+
+func benchmarkNet(sizes ...int) NeuralNetwork {
+	return benchmarkNetWith(WithTanh(), sizes...)
+}
+
+func benchmarkNetWith(activation *func(*Neuron) *Neuron, sizes ...int) NeuralNetwork {
+	specs := make([]LayerSpec, len(sizes))
+	for idx, size := range sizes {
+		specs[idx] = LayerSpec{Size: size, Activation: activation}
+	}
+	return NewNeuralNetFromSpec(specs, WithRand(rand.New(rand.NewPCG(1, 1))), WithInitializer(XavierUniformInitializer()))
+}
+
+func benchmarkExpectations(nn NeuralNetwork, n int) []Expectations {
+	r := rand.New(rand.NewPCG(2, 2))
+	expectations := make([]Expectations, n)
+	for idx := range expectations {
+		in := make([]float64, len(nn[0]))
+		for i := range in {
+			in[i] = r.Float64()
+		}
+		out := make([]float64, len(nn[len(nn)-1]))
+		for i := range out {
+			out[i] = r.Float64()
+		}
+		expectations[idx] = Expectations{Input: in, Output: out}
+	}
+	return expectations
+}
+
+func Benchmark_TrainWorkers(b *testing.B) {
+	for _, sizes := range [][]int{{4, 8, 8, 1}, {64, 256, 256, 8}} {
+		for _, workers := range []int{1, 4} {
+			b.Run(fmt.Sprintf("%v/workers=%v", sizes, workers), func(b *testing.B) {
+				nn := benchmarkNet(sizes...)
+				expectations := benchmarkExpectations(nn, 8)
+				b.ReportAllocs()
+				b.ResetTimer()
+				for range b.N {
+					_, err := nn.Train(expectations, .01, RoundStrategy(1), WithWorkers(workers))
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func Benchmark_NeuronInput(b *testing.B) {
+	nn := benchmarkNet(64, 64, 1)
+	n := nn[2][0]
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		n.Input()
+	}
+}
+
+func Benchmark_Output(b *testing.B) {
+	for _, sizes := range benchmarkShapes {
+		for _, name := range []string{"sigmoid", "relu", "tanh"} {
+			b.Run(fmt.Sprintf("%v/%v", sizes, name), func(b *testing.B) {
+				nn := benchmarkNetWith(benchmarkActivations[name], sizes...)
+				in := benchmarkExpectations(nn, 1)[0].Input
+				b.ReportAllocs()
+				b.ResetTimer()
+				for range b.N {
+					_, err := nn.Output(in)
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func Benchmark_OutputInto(b *testing.B) {
+	for _, sizes := range benchmarkShapes {
+		b.Run(fmt.Sprint(sizes), func(b *testing.B) {
+			nn := benchmarkNet(sizes...)
+			in := benchmarkExpectations(nn, 1)[0].Input
+			inf := nn.Inferencer()
+			dst := make([]float64, len(nn[len(nn)-1]))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				err := inf.OutputInto(dst, in)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func Benchmark_OutputBatch(b *testing.B) {
+	for _, sizes := range benchmarkShapes {
+		for _, rows := range []int{1, 64, 1024} {
+			b.Run(fmt.Sprintf("%v/rows=%v", sizes, rows), func(b *testing.B) {
+				nn := benchmarkNet(sizes...)
+				in := make([][]float64, rows)
+				for idx, e := range benchmarkExpectations(nn, rows) {
+					in[idx] = e.Input
+				}
+				b.ReportAllocs()
+				b.ResetTimer()
+				for range b.N {
+					_, err := nn.OutputBatch(in)
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// Benchmark_TrainEpoch trains a single epoch over 64 expectations per iteration
+func Benchmark_TrainEpoch(b *testing.B) {
+	for _, sizes := range benchmarkShapes {
+		for _, name := range []string{"sigmoid", "relu", "tanh"} {
+			for _, batch := range []int{1, 8, 64} {
+				b.Run(fmt.Sprintf("%v/%v/batch=%v", sizes, name, batch), func(b *testing.B) {
+					nn := benchmarkNetWith(benchmarkActivations[name], sizes...)
+					expectations := benchmarkExpectations(nn, 64)
+					b.ReportAllocs()
+					b.ResetTimer()
+					for range b.N {
+						_, err := nn.Train(expectations, .01, RoundStrategy(1), WithBatchSize(batch))
+						if err != nil {
+							b.Fatal(err)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+func Benchmark_TrainOptimizer(b *testing.B) {
+	optimizers := map[string]Optimizer{
+		"sgd":      SGD(),
+		"momentum": Momentum(.9),
+		"adam":     Adam(.9, .999, 1e-8),
+	}
+	for _, sizes := range benchmarkShapes {
+		for _, name := range []string{"sgd", "momentum", "adam"} {
+			b.Run(fmt.Sprintf("%v/%v", sizes, name), func(b *testing.B) {
+				nn := benchmarkNet(sizes...)
+				expectations := benchmarkExpectations(nn, 64)
+				b.ReportAllocs()
+				b.ResetTimer()
+				for range b.N {
+					_, err := nn.Train(expectations, .01, RoundStrategy(1), WithOptimizer(optimizers[name]), WithBatchSize(8))
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func Benchmark_Serialize(b *testing.B) {
+	for _, sizes := range benchmarkShapes {
+		b.Run(fmt.Sprint(sizes), func(b *testing.B) {
+			nn := benchmarkNet(sizes...)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				_, err := nn.Serialize()
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func Benchmark_NewNeuralNetFromSerialized(b *testing.B) {
+	for _, sizes := range benchmarkShapes {
+		b.Run(fmt.Sprint(sizes), func(b *testing.B) {
+			content, err := benchmarkNet(sizes...).Serialize()
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				_, err := NewNeuralNetFromSerialized(nil, content)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,339 @@
+package qndnn
+
+// DenseLayer keeps the parameters of a layer in contiguous memory; Weights is a
+// row-major matrix with a row per neuron and a column per neuron of the
+// previous layer
+type DenseLayer struct {
+	Inputs    int // columns of Weights, i.e. neurons of the previous layer
+	Weights   []float64
+	Biases    []float64
+	Functions []NeuronFunctions
+
+	// optimizer states laid out like Weights and Biases; nil until an optimizer
+	// is used
+	WeightStates []OptimizerState
+	BiasStates   []OptimizerState
+
+	pendingWeights []float64
+	pendingBiases  []float64
+}
+
+// Dense is the matrix-backed equivalent of a NeuralNetwork, with the same
+// layer indices; its first layer is the input layer, which has no weights
+type Dense []*DenseLayer
+
+// Dense copies the parameters of the network into contiguous layers
+func (nn NeuralNetwork) Dense() Dense {
+	d := make(Dense, len(nn))
+	for l, layer := range nn {
+		inputs := 0
+		if l > 0 {
+			inputs = len(nn[l-1])
+		}
+
+		dl := &DenseLayer{
+			Inputs:    inputs,
+			Weights:   make([]float64, len(layer)*inputs),
+			Biases:    make([]float64, len(layer)),
+			Functions: make([]NeuronFunctions, len(layer)),
+
+			pendingWeights: make([]float64, len(layer)*inputs),
+			pendingBiases:  make([]float64, len(layer)),
+		}
+		for idx, n := range layer {
+			dl.Biases[idx] = n.Bias
+			dl.Functions[idx] = n.Functions
+			if n.BiasOptimizer != nil {
+				dl.states()
+				dl.BiasStates[idx] = *n.BiasOptimizer
+			}
+			for iidx, i := range n.Inputs {
+				dl.Weights[idx*inputs+iidx] = i.Weight
+				if i.Optimizer != nil {
+					dl.states()
+					dl.WeightStates[idx*inputs+iidx] = *i.Optimizer
+				}
+			}
+		}
+		d[l] = dl
+	}
+	return d
+}
+
+// states allocates the optimizer states on first use
+func (dl *DenseLayer) states() {
+	if dl.WeightStates != nil {
+		return
+	}
+	dl.WeightStates = make([]OptimizerState, len(dl.Weights))
+	dl.BiasStates = make([]OptimizerState, len(dl.Biases))
+}
+
+// layer returns the functions applied to the whole layer, if any; layers
+// without neurons have none
+func (dl *DenseLayer) layer() *LayerFunctions {
+	if len(dl.Functions) == 0 {
+		return nil
+	}
+	return dl.Functions[0].Layer
+}
+
+// row returns the weights of neuron r
+func (dl *DenseLayer) row(r int) []float64 {
+	return dl.Weights[r*dl.Inputs : (r+1)*dl.Inputs]
+}
+
+// Network converts the dense layers back into a graph of neurons
+func (d Dense) Network() NeuralNetwork {
+	nn := make(NeuralNetwork, len(d))
+	for l, dl := range d {
+		nn[l] = make([]*Neuron, len(dl.Biases))
+		for idx := range nn[l] {
+			n := &Neuron{Functions: dl.Functions[idx]}
+			if l == 0 {
+				init := 1.0
+				n.Preset = &init
+			} else {
+				n.Inputs = make([]*Input, len(nn[l-1]))
+				for iidx, pn := range nn[l-1] {
+					n.Inputs[iidx] = &Input{N: pn}
+				}
+			}
+			nn[l][idx] = n
+		}
+	}
+	d.CopyTo(nn)
+	return nn
+}
+
+// CopyTo writes weights, biases and optimizer states back into a network of the
+// same shape, e.g. the one the dense layers were created from
+func (d Dense) CopyTo(nn NeuralNetwork) {
+	for l, dl := range d {
+		for idx, n := range nn[l] {
+			n.Bias = dl.Biases[idx]
+			n.BiasOptimizer = setState(n.BiasOptimizer, dl.BiasStates, idx)
+			for iidx, i := range n.Inputs {
+				i.Weight = dl.Weights[idx*dl.Inputs+iidx]
+				i.Optimizer = setState(i.Optimizer, dl.WeightStates, idx*dl.Inputs+iidx)
+			}
+		}
+	}
+}
+
+// setState overwrites the state in place if there is one already; without
+// states no optimizer was used, so there is none to keep
+func setState(s *OptimizerState, states []OptimizerState, idx int) *OptimizerState {
+	if states == nil {
+		return nil
+	}
+	if s == nil {
+		s = &OptimizerState{}
+	}
+	*s = states[idx]
+	return s
+}
+
+func (d Dense) newPass() *pass {
+	sizes := make([]int, len(d))
+	for idx, dl := range d {
+		sizes[idx] = len(dl.Biases)
+	}
+	return newPass(sizes...)
+}
+
+// Output evaluates the dense layers for the given input
+func (d Dense) Output(in []float64) ([]float64, error) {
+	return d.Inferencer().Output(in)
+}
+
+// forward multiplies the activations of every layer with the weight matrix of
+// the next one
+func (d Dense) forward(p *pass, in []float64) {
+	copy(p.pre[0], in)
+	copy(p.act[0], in)
+
+	for l := 1; l < len(d); l++ {
+		if p.workers <= 1 {
+			d.forwardRows(p, l, 0, len(d[l].Biases)) // no closure, so inference doesn't allocate
+		} else {
+			l := l // only the copy escapes into the closure
+			parallel(p.workers, len(d[l].Biases), func(from int, to int) {
+				d.forwardRows(p, l, from, to)
+			})
+		}
+
+		if f := d[l].layer(); f != nil {
+			f.Activation(p.pre[l], p.act[l])
+		}
+	}
+}
+
+// forwardRows evaluates the neurons [from, to) of layer l
+func (d Dense) forwardRows(p *pass, l int, from int, to int) {
+	dl := d[l]
+	prev := p.act[l-1]
+	for r := from; r < to; r++ {
+		v := 0.0
+		for c, w := range dl.row(r) {
+			v += prev[c] * w
+		}
+		v += dl.Biases[r]
+		p.pre[l][r] = v
+		p.act[l][r] = dl.Functions[r].Activation(v)
+	}
+}
+
+// backward expects the deltas of the last layer to be set and propagates them
+// layer by layer towards the input, tracking the pending weight (and bias)
+// changes
+func (d Dense) backward(p *pass, learningRate float64, biases bool) {
+	for l := len(d) - 1; l > 0; l-- {
+		dl := d[l]
+		prev := p.act[l-1]
+		parallel(p.workers, len(dl.Biases), func(from int, to int) {
+			for r := from; r < to; r++ {
+				delta := p.delta[l][r]
+				pending := dl.pendingWeights[r*dl.Inputs : (r+1)*dl.Inputs]
+				for c := range pending {
+					pending[c] += learningRate * delta * prev[c]
+				}
+				if biases {
+					dl.pendingBiases[r] += learningRate * delta
+				}
+			}
+		})
+
+		if l == 1 {
+			break // input layer has nothing to learn
+		}
+
+		// the transposed weights carry the deltas to the previous layer; every
+		// worker walks the rows in order for its own slice of columns
+		below := d[l-1]
+		layer := below.layer()
+		parallel(p.workers, dl.Inputs, func(from int, to int) {
+			derivatives := p.derivative[l-1][from:to]
+			acc := p.delta[l-1][from:to]
+			for c := range acc {
+				derivatives[c] = 1.0 // layer activations are derived for the whole layer below
+				if layer == nil {
+					derivatives[c] = below.Functions[from+c].derive(p.pre[l-1][from+c], p.act[l-1][from+c])
+				}
+				acc[c] = 0
+			}
+			for r, delta := range p.delta[l] {
+				for c, w := range dl.row(r)[from:to] {
+					acc[c] += derivatives[c] * w * delta
+				}
+			}
+		})
+
+		if layer != nil {
+			layer.Derivative(p.act[l-1], p.delta[l-1], p.delta[l-1])
+		}
+	}
+}
+
+// outputDeltas sets the deltas of the last layer for the expected output and
+// returns the errors to report to the strategy
+func (d Dense) outputDeltas(p *pass, c TrainConfig, expected []float64) []float64 {
+	last := len(d) - 1
+	dl := d[last]
+	layer := dl.layer()
+
+	errs := make([]float64, len(dl.Biases))
+	for idx, f := range dl.Functions {
+		in := p.pre[last][idx]
+		out := p.act[last][idx]
+		if c.Loss != nil {
+			errs[idx] = c.Loss.Value(out, expected[idx])
+			if layer != nil {
+				p.delta[last][idx] = c.Loss.Gradient(out, expected[idx])
+			} else {
+				p.delta[last][idx] = f.derive(in, out) * c.Loss.Gradient(out, expected[idx])
+			}
+			continue
+		}
+
+		err := out - expected[idx]
+		errs[idx] = err
+		if layer != nil {
+			p.delta[last][idx] = err
+			continue
+		}
+		derivative := f.derive(in, out)
+		delta := err * derivative
+		p.delta[last][idx] = derivative * 1.0 * delta // delta is taken full
+	}
+
+	if layer == nil {
+		return errs
+	}
+
+	if _, ok := c.Loss.(categoricalCrossEntropy); ok && layer == softmaxLayer {
+		// softmax and cross entropy combined simplify to the difference, which
+		// stays stable for probabilities close to 0
+		for idx, out := range p.act[last] {
+			p.delta[last][idx] = out - expected[idx]
+		}
+	} else {
+		layer.Derivative(p.act[last], p.delta[last], p.delta[last])
+	}
+	return errs
+}
+
+// average scales all pending changes down to the mean over n expectations
+func (d Dense) average(n int) {
+	if n == 1 {
+		return
+	}
+
+	for _, dl := range d {
+		for idx := range dl.pendingWeights {
+			dl.pendingWeights[idx] /= float64(n)
+		}
+		for idx := range dl.pendingBiases {
+			dl.pendingBiases[idx] /= float64(n)
+		}
+	}
+}
+
+// apply subtracts all pending changes, passed through the optimizer if one is
+// configured
+func (d Dense) apply(c TrainConfig, learningRate float64) {
+	for _, dl := range d[1:] { // input layer has nothing to update
+		if _, stateless := c.Optimizer.(sgd); c.Optimizer == nil || stateless { // no state to keep
+			for idx, change := range dl.pendingWeights {
+				dl.Weights[idx] -= change
+				dl.pendingWeights[idx] = 0
+			}
+			for idx, change := range dl.pendingBiases {
+				dl.Biases[idx] -= change
+				dl.pendingBiases[idx] = 0
+			}
+			continue
+		}
+
+		dl.states()
+		for idx, change := range dl.pendingWeights {
+			dl.Weights[idx] -= c.Optimizer.Step(&dl.WeightStates[idx], change, learningRate)
+			dl.pendingWeights[idx] = 0
+		}
+		if c.FreezeBiases {
+			continue // the state would move the biases without any change pending
+		}
+		for idx, change := range dl.pendingBiases {
+			dl.Biases[idx] -= c.Optimizer.Step(&dl.BiasStates[idx], change, learningRate)
+			dl.pendingBiases[idx] = 0
+		}
+	}
+}
+
+// discard drops all pending changes
+func (d Dense) discard() {
+	for _, dl := range d {
+		clear(dl.pendingWeights)
+		clear(dl.pendingBiases)
+	}
+}
@@ -0,0 +1,146 @@
+package qndnn
+
+import "testing"
+
+func Test_Dense(t *testing.T) {
+	spec := []LayerSpec{
+		{Size: 3},
+		{Size: 4, Activation: WithRelu()},
+		{Size: 2, Activation: WithSigmoid()},
+	}
+
+	t.Run("layout", func(t *testing.T) {
+		nn := NewNeuralNetFromSpec(spec)
+		d := nn.Dense()
+		if len(d) != len(nn) {
+			t.Fatalf("expected %v layers, got %v", len(nn), len(d))
+		}
+		if d[0].Inputs != 0 || len(d[0].Weights) != 0 {
+			t.Error("expected input layer without weights")
+		}
+
+		for l := 1; l < len(nn); l++ {
+			if d[l].Inputs != len(nn[l-1]) {
+				t.Errorf("layer #%v; expected %v inputs, got %v", l, len(nn[l-1]), d[l].Inputs)
+			}
+			for idx, n := range nn[l] {
+				if d[l].Biases[idx] != n.Bias {
+					t.Errorf("bias of neuron #%v in layer #%v differs", idx, l)
+				}
+				for iidx, i := range n.Inputs {
+					if d[l].Weights[idx*d[l].Inputs+iidx] != i.Weight {
+						t.Errorf("weight #%v of neuron #%v in layer #%v differs", iidx, idx, l)
+					}
+				}
+			}
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		nn := NewNeuralNetFromSpec(spec)
+		expected, err := nn.Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := nn.Dense().Network().Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected != got {
+			t.Errorf("expected %s, got %s", expected, got)
+		}
+	})
+
+	t.Run("output", func(t *testing.T) {
+		nn := NewNeuralNetFromSpec(spec)
+		in := []float64{.2, -.4, .9}
+		expected, err := nn.Output(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := nn.Dense().Output(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for idx := range expected {
+			if expected[idx] != got[idx] {
+				t.Errorf("expected %v, got %v", expected, got)
+			}
+		}
+
+		_, err = nn.Dense().Output([]float64{1})
+		if err == nil {
+			t.Error("expected error for mismatching input")
+		}
+	})
+
+	t.Run("empty layer", func(t *testing.T) {
+		nn := NewNeuralNet(nil, 2, 0, 1)
+		nn[2][0].Bias = .5
+		out, err := nn.Output([]float64{1, 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out[0] != Sigmoid(.5) {
+			t.Errorf("expected output of bias only; wanted %v, got %v", Sigmoid(.5), out[0])
+		}
+
+		_, err = nn.Train([]Expectations{{Input: []float64{1, 2}, Output: []float64{1}}}, .5, RoundStrategy(2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nn[2][0].Bias == .5 {
+			t.Error("expected bias to be trained")
+		}
+	})
+
+	t.Run("copy optimizer states", func(t *testing.T) {
+		nn := NewNeuralNetFromSpec(spec)
+		d := nn.Dense()
+		d.CopyTo(nn)
+		if nn[1][0].Inputs[0].Optimizer != nil || nn[1][0].BiasOptimizer != nil {
+			t.Error("expected no optimizer state before an optimizer is used")
+		}
+
+		d[1].states()
+		d[1].WeightStates[1].Velocity = 3
+		d[1].BiasStates[2].Cache = 4
+		d.CopyTo(nn)
+		if v := nn[1][0].Inputs[1].Optimizer.Velocity; v != 3 {
+			t.Errorf("expected velocity 3, got %v", v)
+		}
+		if c := nn[1][2].BiasOptimizer.Cache; c != 4 {
+			t.Errorf("expected cache 4, got %v", c)
+		}
+
+		if s := nn.Dense()[1].WeightStates[1]; s.Velocity != 3 {
+			t.Errorf("expected state to be read back, got %+v", s)
+		}
+	})
+
+	t.Run("network in sync during training", func(t *testing.T) {
+		nn := NewNeuralNetFromSpec(spec)
+		expectations := []Expectations{
+			{Input: []float64{1, 0, 1}, Output: []float64{1, 0}},
+			{Input: []float64{0, 1, 0}, Output: []float64{0, 1}},
+		}
+
+		var outputs [][]float64
+		strategy := func(_ TrainingState) bool {
+			out, err := nn.Output(expectations[0].Input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			outputs = append(outputs, out)
+			return len(outputs) < 3
+		}
+		_, err := nn.Train(expectations, .5, strategy)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if outputs[0][0] == outputs[2][0] {
+			t.Error("expected strategy to observe the trained network")
+		}
+	})
+}
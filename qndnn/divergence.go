@@ -0,0 +1,118 @@
+package qndnn
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrDiverged matches every DivergedError, to be checked with errors.Is
+var ErrDiverged = errors.New("training diverged")
+
+// DivergedError is returned by Train as soon as an error, gradient, weight or
+// bias isn't finite anymore
+type DivergedError struct {
+	Epoch    int
+	Layer    int
+	Neuron   int
+	Cause    string // one of 'error', 'gradient', 'weight' or 'bias'
+	Restored bool   // if the last finite weights were restored; see WithRestoreOnDivergence
+}
+
+func (e *DivergedError) Error() string {
+	return fmt.Sprintf(
+		"training diverged; %s of neuron #%v in layer #%v isn't finite (epoch #%v)",
+		e.Cause,
+		e.Neuron,
+		e.Layer,
+		e.Epoch,
+	)
+}
+
+func (e *DivergedError) Is(target error) bool {
+	return target == ErrDiverged
+}
+
+func finite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// check looks for non-finite errors and deltas of the last forward and backward
+// pass
+func (p *pass) check(errs []float64, epoch int) *DivergedError {
+	last := len(p.delta) - 1
+	for idx, err := range errs {
+		if !finite(err) {
+			return &DivergedError{Epoch: epoch, Layer: last, Neuron: idx, Cause: "error"}
+		}
+	}
+
+	for l := last; l > 0; l-- {
+		for idx, d := range p.delta[l] {
+			if !finite(d) {
+				return &DivergedError{Epoch: epoch, Layer: l, Neuron: idx, Cause: "gradient"}
+			}
+		}
+	}
+	return nil
+}
+
+// checkParameters looks for non-finite weights and biases
+func (d Dense) checkParameters(epoch int) *DivergedError {
+	for l, dl := range d {
+		for idx, b := range dl.Biases {
+			if !finite(b) {
+				return &DivergedError{Epoch: epoch, Layer: l, Neuron: idx, Cause: "bias"}
+			}
+			for _, w := range dl.row(idx) {
+				if !finite(w) {
+					return &DivergedError{Epoch: epoch, Layer: l, Neuron: idx, Cause: "weight"}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// snapshot is a copy of all weights, biases and optimizer states
+type snapshot struct {
+	weights      [][]float64
+	biases       [][]float64
+	weightStates [][]OptimizerState
+	biasStates   [][]OptimizerState
+}
+
+// take copies the current parameters of the dense layers into the snapshot,
+// reusing its buffers
+func (s *snapshot) take(d Dense) {
+	if s.weights == nil {
+		s.weights = make([][]float64, len(d))
+		s.biases = make([][]float64, len(d))
+		s.weightStates = make([][]OptimizerState, len(d))
+		s.biasStates = make([][]OptimizerState, len(d))
+	}
+	for l, dl := range d {
+		s.weights[l] = append(s.weights[l][:0], dl.Weights...)
+		s.biases[l] = append(s.biases[l][:0], dl.Biases...)
+		s.weightStates[l] = copyStates(s.weightStates[l], dl.WeightStates)
+		s.biasStates[l] = copyStates(s.biasStates[l], dl.BiasStates)
+	}
+}
+
+// restore sets the parameters of the dense layers back to the snapshot
+func (s *snapshot) restore(d Dense) {
+	for l, dl := range d {
+		copy(dl.Weights, s.weights[l])
+		copy(dl.Biases, s.biases[l])
+		dl.WeightStates = copyStates(dl.WeightStates, s.weightStates[l])
+		dl.BiasStates = copyStates(dl.BiasStates, s.biasStates[l])
+	}
+}
+
+// copyStates copies src into dst, keeping nil for optimizer states never used
+func copyStates(dst []OptimizerState, src []OptimizerState) []OptimizerState {
+	if src == nil {
+		return nil
+	}
+	return append(dst[:0], src...)
+}
@@ -0,0 +1,79 @@
+package qndnn
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func Test_Divergence(t *testing.T) {
+	exploding := []Expectations{
+		{[]float64{10}, []float64{1000}},
+		{[]float64{-10}, []float64{-1000}},
+	}
+
+	t.Run("exploding", func(t *testing.T) {
+		nn := NewNeuralNet(WithLinear(), 1, 2, 1)
+		_, err := nn.Train(exploding, 10, RoundStrategy(1000))
+		if !errors.Is(err, ErrDiverged) {
+			t.Fatalf("expected divergence, got '%v'", err)
+		}
+
+		var d *DivergedError
+		if !errors.As(err, &d) {
+			t.Fatal("expected DivergedError")
+		}
+		if d.Restored || d.Layer < 0 || d.Layer >= len(nn) || d.Epoch < 0 {
+			t.Errorf("unexpected divergence details: %+v", d)
+		}
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		nn := NewNeuralNet(WithLinear(), 1, 2, 1)
+		_, err := nn.Train(exploding, 10, RoundStrategy(1000), WithRestoreOnDivergence(), WithOptimizer(Momentum(.9)))
+		var d *DivergedError
+		if !errors.As(err, &d) {
+			t.Fatalf("expected divergence, got '%v'", err)
+		}
+		if !d.Restored {
+			t.Error("expected weights to be restored")
+		}
+
+		if d := nn.Dense().checkParameters(0); d != nil {
+			t.Errorf("expected finite parameters, got '%v'", d)
+		}
+		for _, l := range nn {
+			for _, n := range l {
+				for _, i := range n.Inputs {
+					if i.Optimizer == nil || !finite(i.Optimizer.Velocity) {
+						t.Error("expected finite optimizer state")
+					}
+				}
+			}
+		}
+
+		_, err = nn.Output([]float64{1})
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("non-finite error", func(t *testing.T) {
+		nn := NewNeuralNet(nil, 1, 2, 1)
+		_, err := nn.Train([]Expectations{{[]float64{1}, []float64{math.NaN()}}}, .5, RoundStrategy(10))
+		var d *DivergedError
+		if !errors.As(err, &d) {
+			t.Fatalf("expected divergence, got '%v'", err)
+		}
+		if d.Cause != "error" || d.Layer != 2 || d.Neuron != 0 || d.Epoch != 0 {
+			t.Errorf("unexpected divergence details: %+v", d)
+		}
+	})
+
+	t.Run("message", func(t *testing.T) {
+		err := &DivergedError{Epoch: 3, Layer: 2, Neuron: 1, Cause: "weight"}
+		if err.Error() != "training diverged; weight of neuron #1 in layer #2 isn't finite (epoch #3)" {
+			t.Errorf("unexpected message '%v'", err.Error())
+		}
+	})
+}
@@ -1,13 +1,25 @@
 package qndnn
 
-import "math"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
 
 var (
 	WithSigmoid = func() *func(*Neuron) *Neuron {
 		f := func(n *Neuron) *Neuron {
 			n.Functions = NeuronFunctions{
-				Activation: Sigmoid,
-				Derivative: DerivativeSigmoid,
+				Name:             "sigmoid",
+				Activation:       Sigmoid,
+				Derivative:       DerivativeSigmoid,
+				OutputDerivative: DerivativeSigmoidOutput,
 			}
 			return n
 		}
@@ -16,8 +28,10 @@ var (
 	WithRelu = func() *func(*Neuron) *Neuron {
 		f := func(n *Neuron) *Neuron {
 			n.Functions = NeuronFunctions{
-				Activation: Relu,
-				Derivative: DerivativeRelu,
+				Name:             "relu",
+				Activation:       Relu,
+				Derivative:       DerivativeRelu,
+				OutputDerivative: DerivativeReluOutput,
 			}
 			return n
 		}
@@ -26,27 +40,273 @@ var (
 	WithTanh = func() *func(*Neuron) *Neuron {
 		f := func(n *Neuron) *Neuron {
 			n.Functions = NeuronFunctions{
-				Activation: HyperbolicTangent,
-				Derivative: DerivativeHyperbolicTangent,
+				Name:             "tanh",
+				Activation:       HyperbolicTangent,
+				Derivative:       DerivativeHyperbolicTangent,
+				OutputDerivative: DerivativeHyperbolicTangentOutput,
 			}
 			return n
 		}
 		return &f
 	}
+	// WithSoftmax turns the layer into a probability distribution; it must be used
+	// for all neurons of a layer, Value of a single neuron returns its logit
+	WithSoftmax = func() *func(*Neuron) *Neuron {
+		f := func(n *Neuron) *Neuron {
+			n.Functions = NeuronFunctions{
+				Name:       "softmax",
+				Activation: Linear,
+				Derivative: DerivativeLinear,
+				Layer:      softmaxLayer,
+			}
+			return n
+		}
+		return &f
+	}
+	// WithLeakyRelu passes negative input scaled by slope (commonly 0.01)
+	WithLeakyRelu = func(slope float64) *func(*Neuron) *Neuron {
+		return withFunctions(NeuronFunctions{
+			Name:       parameterizedName("leaky_relu", slope),
+			Activation: func(x float64) float64 { return LeakyRelu(x, slope) },
+			Derivative: func(x float64) float64 { return DerivativeLeakyRelu(x, slope) },
+		})
+	}
+	// WithElu saturates negative input at -alpha (commonly 1)
+	WithElu = func(alpha float64) *func(*Neuron) *Neuron {
+		return withFunctions(NeuronFunctions{
+			Name:       parameterizedName("elu", alpha),
+			Activation: func(x float64) float64 { return Elu(x, alpha) },
+			Derivative: func(x float64) float64 { return DerivativeElu(x, alpha) },
+		})
+	}
+	WithSelu = func() *func(*Neuron) *Neuron {
+		return withFunctions(NeuronFunctions{
+			Name:       "selu",
+			Activation: Selu,
+			Derivative: DerivativeSelu,
+		})
+	}
+	WithGelu = func() *func(*Neuron) *Neuron {
+		return withFunctions(NeuronFunctions{
+			Name:       "gelu",
+			Activation: Gelu,
+			Derivative: DerivativeGelu,
+		})
+	}
+	WithSwish = func() *func(*Neuron) *Neuron {
+		return withFunctions(NeuronFunctions{
+			Name:       "swish",
+			Activation: Swish,
+			Derivative: DerivativeSwish,
+		})
+	}
+	WithSoftplus = func() *func(*Neuron) *Neuron {
+		return withFunctions(NeuronFunctions{
+			Name:       "softplus",
+			Activation: Softplus,
+			Derivative: DerivativeSoftplus,
+		})
+	}
+	WithSoftsign = func() *func(*Neuron) *Neuron {
+		return withFunctions(NeuronFunctions{
+			Name:       "softsign",
+			Activation: Softsign,
+			Derivative: DerivativeSoftsign,
+		})
+	}
+	// WithLinear passes the input as is, e.g. for unbounded regression outputs
+	WithLinear = func() *func(*Neuron) *Neuron {
+		return withFunctions(NeuronFunctions{
+			Name:             "linear",
+			Activation:       Linear,
+			Derivative:       DerivativeLinear,
+			OutputDerivative: DerivativeLinearOutput,
+		})
+	}
 )
 
+func withFunctions(functions NeuronFunctions) *func(*Neuron) *Neuron {
+	f := func(n *Neuron) *Neuron {
+		n.Functions = functions
+		return n
+	}
+	return &f
+}
+
+// parameterizedName keeps the parameter in the name, e.g. 'elu(0.5)', so it
+// can be restored from serialized networks
+func parameterizedName(name string, parameter float64) string {
+	return fmt.Sprintf("%s(%s)", name, strconv.FormatFloat(parameter, 'g', -1, 64))
+}
+
+// activations resolves activation names, e.g. the ones stored with serialized
+// networks; see RegisterActivation
+var (
+	activations = map[string]func() *func(*Neuron) *Neuron{
+		"sigmoid":  WithSigmoid,
+		"relu":     WithRelu,
+		"tanh":     WithTanh,
+		"softmax":  WithSoftmax,
+		"selu":     WithSelu,
+		"gelu":     WithGelu,
+		"swish":    WithSwish,
+		"softplus": WithSoftplus,
+		"softsign": WithSoftsign,
+		"linear":   WithLinear,
+	}
+	// parameterizedActivations are resolved by names like 'leaky_relu(0.01)'
+	parameterizedActivations = map[string]parameterizedActivation{
+		"leaky_relu": {parameter: "slope", create: WithLeakyRelu},
+		"elu":        {parameter: "alpha", create: WithElu},
+	}
+	activationsLock sync.RWMutex
+)
+
+// parameterizedActivation creates an activation for its parameter, which is
+// listed by name in Activations
+type parameterizedActivation struct {
+	parameter string
+	create    func(float64) *func(*Neuron) *Neuron
+}
+
+// RegisterActivation makes user-defined functions available by name, so they
+// can be resolved with WithActivation and restored from serialized networks;
+// register them before deserializing
+func RegisterActivation(name string, functions NeuronFunctions) error {
+	if name == "" {
+		return errors.New("activation name must not be empty")
+	}
+
+	if functions.Activation == nil || functions.Derivative == nil {
+		return fmt.Errorf("activation '%v' requires activation and derivative", name)
+	}
+
+	activationsLock.Lock()
+	defer activationsLock.Unlock()
+	if _, ok := activations[name]; ok {
+		return fmt.Errorf("activation '%v' is already registered", name)
+	}
+
+	if _, ok := parameterizedActivations[name]; ok {
+		return fmt.Errorf("activation '%v' is already registered", name)
+	}
+
+	functions.Name = name
+	activations[name] = func() *func(*Neuron) *Neuron {
+		return withFunctions(functions)
+	}
+	return nil
+}
+
+// WithActivation resolves a registered activation by name; parameterized
+// activations take their parameter in parentheses, e.g. 'leaky_relu(0.01)'
+func WithActivation(name string) (*func(*Neuron) *Neuron, error) {
+	activationsLock.RLock()
+	create, ok := activations[name]
+	activationsLock.RUnlock()
+	if ok {
+		return create(), nil
+	}
+
+	base, parameter, ok := strings.Cut(name, "(")
+	parameterized, found := parameterizedActivations[base]
+	if !ok || !found || !strings.HasSuffix(parameter, ")") {
+		return nil, fmt.Errorf("unknown activation '%v'; available are '%v'", name, strings.Join(Activations(), "|"))
+	}
+
+	p, err := strconv.ParseFloat(strings.TrimSuffix(parameter, ")"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameter of activation '%v': %w", name, err)
+	}
+	return parameterized.create(p), nil
+}
+
+// Activations lists the names of all registered activations; parameterized ones
+// are listed with the name of their parameter
+func Activations() []string {
+	activationsLock.RLock()
+	defer activationsLock.RUnlock()
+	names := slices.Collect(maps.Keys(activations))
+	for name, p := range parameterizedActivations {
+		names = append(names, fmt.Sprintf("%v(%v)", name, p.parameter))
+	}
+	slices.Sort(names)
+	return names
+}
+
 type NeuronFunctions struct {
+	Name       string // used to persist the functions; see RegisterActivation
 	Activation func(float64) float64
 	Derivative func(float64) float64
+	Layer      *LayerFunctions // if set, activates the whole layer instead of Activation
+
+	// OutputDerivative is the derivative expressed by the activated output; if
+	// set, it is used instead of Derivative to not compute the activation again
+	OutputDerivative func(float64) float64
+}
+
+// derive returns the derivative for a neuron with input in and output out
+func (f NeuronFunctions) derive(in float64, out float64) float64 {
+	if f.OutputDerivative != nil {
+		return f.OutputDerivative(out)
+	}
+	return f.Derivative(in)
+}
+
+func (f NeuronFunctions) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.Name)
+}
+
+func (f *NeuronFunctions) UnmarshalJSON(b []byte) error {
+	var name string
+	err := json.Unmarshal(b, &name)
+	if err != nil {
+		return err
+	}
+
+	if name == "" {
+		*f = NeuronFunctions{}
+		return nil
+	}
+
+	create, err := WithActivation(name)
+	if err != nil {
+		return err
+	}
+	*f = (*create)(&Neuron{}).Functions
+	return nil
+}
+
+// LayerFunctions activate all neurons of a layer at once, for activations that
+// depend on the whole layer; Derivative sets delta to the gradient with respect
+// to the layer input, given the gradient with respect to the activated output
+type LayerFunctions struct {
+	Activation func(in []float64, out []float64)
+	Derivative func(out []float64, grad []float64, delta []float64)
+}
+
+var softmaxLayer = &LayerFunctions{
+	Activation: Softmax,
+	Derivative: DerivativeSoftmax,
 }
 
+// Sigmoid branches on the sign of x, so the exponential never overflows
 func Sigmoid(x float64) float64 {
-	return 1.0 / (1.0 + math.Pow(math.E, -1.0*x))
+	if x >= 0 {
+		return 1 / (1 + math.Exp(-x))
+	}
+	e := math.Exp(x)
+	return e / (1 + e)
 }
 
 func DerivativeSigmoid(y float64) float64 {
-	x := Sigmoid(y)
-	return x * (1 - x)
+	return DerivativeSigmoidOutput(Sigmoid(y))
+}
+
+// DerivativeSigmoidOutput is the derivative expressed by the already computed
+// activation s = Sigmoid(y)
+func DerivativeSigmoidOutput(s float64) float64 {
+	return s * (1 - s)
 }
 
 func Relu(x float64) float64 {
@@ -54,7 +314,11 @@ func Relu(x float64) float64 {
 }
 
 func DerivativeRelu(y float64) float64 {
-	if Relu(y) > 0 {
+	return DerivativeReluOutput(Relu(y))
+}
+
+func DerivativeReluOutput(r float64) float64 {
+	if r > 0 {
 		return 1
 	} else {
 		return 0
@@ -62,9 +326,143 @@ func DerivativeRelu(y float64) float64 {
 }
 
 func HyperbolicTangent(x float64) float64 {
-	return (math.Pow(math.E, x) - math.Pow(math.E, -x)) / (math.Pow(math.E, x) + math.Pow(math.E, -x))
+	return math.Tanh(x)
 }
 
 func DerivativeHyperbolicTangent(y float64) float64 {
-	return 1 - math.Pow(HyperbolicTangent(y), 2)
+	return DerivativeHyperbolicTangentOutput(HyperbolicTangent(y))
+}
+
+func DerivativeHyperbolicTangentOutput(t float64) float64 {
+	return 1 - t*t
+}
+
+func Softmax(in []float64, out []float64) {
+	m := math.Inf(-1)
+	for _, v := range in {
+		m = math.Max(m, v)
+	}
+
+	sum := 0.0
+	for idx, v := range in {
+		out[idx] = math.Exp(v - m) // shifted by max to not overflow
+		sum += out[idx]
+	}
+
+	for idx := range out {
+		out[idx] /= sum
+	}
+}
+
+// DerivativeSoftmax applies the transposed jacobian of softmax to grad; delta
+// may be the same slice as grad
+func DerivativeSoftmax(out []float64, grad []float64, delta []float64) {
+	s := 0.0
+	for idx, p := range out {
+		s += p * grad[idx]
+	}
+
+	for idx, p := range out {
+		delta[idx] = p * (grad[idx] - s)
+	}
+}
+
+// ArgMax returns the index of the largest value, or -1 if values are empty
+func ArgMax(values []float64) int {
+	m := -1
+	for idx, v := range values {
+		if m == -1 || v > values[m] {
+			m = idx
+		}
+	}
+	return m
+}
+
+func LeakyRelu(x float64, slope float64) float64 {
+	if x > 0 {
+		return x
+	}
+	return slope * x
+}
+
+func DerivativeLeakyRelu(y float64, slope float64) float64 {
+	if y > 0 {
+		return 1
+	}
+	return slope
+}
+
+func Elu(x float64, alpha float64) float64 {
+	if x > 0 {
+		return x
+	}
+	return alpha * math.Expm1(x)
+}
+
+func DerivativeElu(y float64, alpha float64) float64 {
+	if y > 0 {
+		return 1
+	}
+	return alpha * math.Exp(y)
+}
+
+// constants of SELU; see Klambauer et al. (2017), Self-Normalizing Neural Networks
+const (
+	seluLambda = 1.0507009873554804934193349852946
+	seluAlpha  = 1.6732632423543772848170429916717
+)
+
+func Selu(x float64) float64 {
+	return seluLambda * Elu(x, seluAlpha)
+}
+
+func DerivativeSelu(y float64) float64 {
+	return seluLambda * DerivativeElu(y, seluAlpha)
+}
+
+func Gelu(x float64) float64 {
+	return 0.5 * x * (1 + math.Erf(x/math.Sqrt2))
+}
+
+func DerivativeGelu(y float64) float64 {
+	cdf := 0.5 * (1 + math.Erf(y/math.Sqrt2))
+	pdf := math.Exp(-0.5*y*y) / math.Sqrt(2*math.Pi)
+	return cdf + y*pdf
+}
+
+func Swish(x float64) float64 {
+	return x * Sigmoid(x)
+}
+
+func DerivativeSwish(y float64) float64 {
+	s := Sigmoid(y)
+	return s + y*s*(1-s)
+}
+
+func Softplus(x float64) float64 {
+	return math.Max(x, 0) + math.Log1p(math.Exp(-math.Abs(x))) // doesn't overflow for large x
+}
+
+func DerivativeSoftplus(y float64) float64 {
+	return Sigmoid(y)
+}
+
+func Softsign(x float64) float64 {
+	return x / (1 + math.Abs(x))
+}
+
+func DerivativeSoftsign(y float64) float64 {
+	return 1 / math.Pow(1+math.Abs(y), 2)
+}
+
+func Linear(x float64) float64 {
+	return x
+}
+
+func DerivativeLinear(_ float64) float64 {
+	return 1
+}
+
+func DerivativeLinearOutput(_ float64) float64 {
+	return 1
 }
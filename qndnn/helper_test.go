@@ -2,15 +2,19 @@ package qndnn
 
 import (
 	"fmt"
+	"math"
+	"slices"
 	"testing"
 )
 
 func Test_Sigmoid(t *testing.T) {
 	t.Run("regular", func(t *testing.T) {
 		for _, tc := range []struct{ in, out float64 }{
-			{-4, 0.017986209962091562},
+			{-4, 0.017986209962091555},
 			{1, 0.7310585786300049},
 			{42, 1},
+			{-1000, 0},
+			{1000, 1},
 		} {
 			t.Run(fmt.Sprintf("%v_%v", tc.in, tc.out), func(t *testing.T) {
 				out := Sigmoid(tc.in)
@@ -36,6 +40,30 @@ func Test_Sigmoid(t *testing.T) {
 	})
 }
 
+func Test_OutputDerivative(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		activation func(float64) float64
+		derivative func(float64) float64
+		output     func(float64) float64
+	}{
+		{"sigmoid", Sigmoid, DerivativeSigmoid, DerivativeSigmoidOutput},
+		{"relu", Relu, DerivativeRelu, DerivativeReluOutput},
+		{"tanh", HyperbolicTangent, DerivativeHyperbolicTangent, DerivativeHyperbolicTangentOutput},
+		{"linear", Linear, DerivativeLinear, DerivativeLinearOutput},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, x := range []float64{-1000, -4, -.5, 0, .5, 4, 1000} {
+				d := tc.derivative(x)
+				o := tc.output(tc.activation(x))
+				if d != o || math.IsNaN(o) {
+					t.Errorf("failed at %v; expected '%v', got '%v'", x, d, o)
+				}
+			}
+		})
+	}
+}
+
 func Test_Relu(t *testing.T) {
 	t.Run("regular", func(t *testing.T) {
 		for _, tc := range []struct{ in, out float64 }{
@@ -71,10 +99,12 @@ func Test_Relu(t *testing.T) {
 func Test_HyperbolicTangent(t *testing.T) {
 	t.Run("regular", func(t *testing.T) {
 		for _, tc := range []struct{ in, out float64 }{
-			{-4, -0.9993292997390669},
+			{-4, -0.999329299739067},
 			{0, 0},
 			{1, 0.7615941559557649},
 			{42, 1},
+			{-1000, -1},
+			{1000, 1},
 		} {
 			t.Run(fmt.Sprintf("%v_%v", tc.in, tc.out), func(t *testing.T) {
 				out := HyperbolicTangent(tc.in)
@@ -87,7 +117,7 @@ func Test_HyperbolicTangent(t *testing.T) {
 
 	t.Run("derivative", func(t *testing.T) {
 		for _, tc := range []struct{ in, out float64 }{
-			{-4, 0.0013409506830260876},
+			{-4, 0.0013409506830258655},
 			{0, 1},
 			{1, 0.41997434161402614},
 			{42, 0},
@@ -102,6 +132,281 @@ func Test_HyperbolicTangent(t *testing.T) {
 	})
 }
 
+func Test_Activations(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		activation func(float64) float64
+		derivative func(float64) float64
+		cases      []struct{ in, out, derivative float64 }
+	}{
+		{
+			"leaky relu",
+			func(x float64) float64 { return LeakyRelu(x, .1) },
+			func(x float64) float64 { return DerivativeLeakyRelu(x, .1) },
+			[]struct{ in, out, derivative float64 }{{-2, -.2, .1}, {0, 0, .1}, {2, 2, 1}},
+		},
+		{
+			"elu",
+			func(x float64) float64 { return Elu(x, 1) },
+			func(x float64) float64 { return DerivativeElu(x, 1) },
+			[]struct{ in, out, derivative float64 }{{-1, -0.6321205588285577, 0.36787944117144233}, {0, 0, 1}, {2, 2, 1}},
+		},
+		{
+			"selu",
+			Selu,
+			DerivativeSelu,
+			[]struct{ in, out, derivative float64 }{{-1, -1.1113307378125625, 0.6467686030348141}, {1, 1.0507009873554805, 1.0507009873554805}},
+		},
+		{
+			"gelu",
+			Gelu,
+			DerivativeGelu,
+			[]struct{ in, out, derivative float64 }{{-1, -0.15865525393145707, -0.08331547058768637}, {0, 0, .5}, {1, 0.8413447460685429, 1.0833154705876864}},
+		},
+		{
+			"swish",
+			Swish,
+			DerivativeSwish,
+			[]struct{ in, out, derivative float64 }{{-1, -0.2689414213699951, 0.07232948812851325}, {0, 0, .5}, {2, 1.7615941559557646, 1.0907842487848955}},
+		},
+		{
+			"softplus",
+			Softplus,
+			DerivativeSoftplus,
+			[]struct{ in, out, derivative float64 }{{-1, 0.31326168751822286, 0.2689414213699951}, {0, math.Ln2, .5}, {1000, 1000, 1}},
+		},
+		{
+			"softsign",
+			Softsign,
+			DerivativeSoftsign,
+			[]struct{ in, out, derivative float64 }{{-1, -.5, .25}, {0, 0, 1}, {2, 0.6666666666666666, 0.1111111111111111}},
+		},
+		{
+			"linear",
+			Linear,
+			DerivativeLinear,
+			[]struct{ in, out, derivative float64 }{{-4, -4, 1}, {42, 42, 1}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, c := range tc.cases {
+				t.Run(fmt.Sprintf("%v", c.in), func(t *testing.T) {
+					out := tc.activation(c.in)
+					if math.Abs(out-c.out) > 1e-12 {
+						t.Errorf("failed; expected '%v', got '%v'", c.out, out)
+					}
+
+					d := tc.derivative(c.in)
+					if math.Abs(d-c.derivative) > 1e-12 {
+						t.Errorf("failed derivative; expected '%v', got '%v'", c.derivative, d)
+					}
+				})
+			}
+
+			// derivative matches the numerical one apart from kinks at 0
+			h := 1e-6
+			for _, x := range []float64{-3, -.5, .5, 3} {
+				numerical := (tc.activation(x+h) - tc.activation(x-h)) / (2 * h)
+				if d := tc.derivative(x); math.Abs(d-numerical) > 1e-6 {
+					t.Errorf("failed numerical derivative at %v; expected '%v', got '%v'", x, numerical, d)
+				}
+			}
+		})
+	}
+}
+
+func Test_Softmax(t *testing.T) {
+	t.Run("regular", func(t *testing.T) {
+		for _, tc := range []struct{ in, out []float64 }{
+			{[]float64{1, 2, 3}, []float64{0.09003057317038046, 0.24472847105479764, 0.6652409557748218}},
+			{[]float64{0, 0}, []float64{.5, .5}},
+			{[]float64{1000, 1000}, []float64{.5, .5}},
+			{[]float64{-1000, 0}, []float64{0, 1}},
+		} {
+			t.Run(fmt.Sprintf("%v", tc.in), func(t *testing.T) {
+				out := make([]float64, len(tc.in))
+				Softmax(tc.in, out)
+				for idx, o := range out {
+					if math.Abs(o-tc.out[idx]) > 1e-15 {
+						t.Errorf("failed; expected '%v', got '%v'", tc.out, out)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("derivative", func(t *testing.T) {
+		in := []float64{.5, -1, 2}
+		grad := []float64{.3, -.2, .7}
+		out := make([]float64, len(in))
+		Softmax(in, out)
+		delta := make([]float64, len(in))
+		DerivativeSoftmax(out, grad, delta)
+
+		// compare with the numerical gradient of sum(grad * softmax(in))
+		h := 1e-6
+		for idx := range in {
+			f := func(x float64) float64 {
+				shifted := append([]float64{}, in...)
+				shifted[idx] = x
+				o := make([]float64, len(in))
+				Softmax(shifted, o)
+				v := 0.0
+				for oidx, g := range grad {
+					v += g * o[oidx]
+				}
+				return v
+			}
+			numerical := (f(in[idx]+h) - f(in[idx]-h)) / (2 * h)
+			if math.Abs(numerical-delta[idx]) > 1e-8 {
+				t.Errorf("failed #%v; expected '%v', got '%v'", idx, numerical, delta[idx])
+			}
+		}
+	})
+}
+
+func Test_ArgMax(t *testing.T) {
+	for _, tc := range []struct {
+		in  []float64
+		out int
+	}{
+		{[]float64{.1, .7, .2}, 1},
+		{[]float64{3}, 0},
+		{[]float64{-1, -2, -.5}, 2},
+		{[]float64{1, 1}, 0},
+		{nil, -1},
+	} {
+		t.Run(fmt.Sprintf("%v", tc.in), func(t *testing.T) {
+			if out := ArgMax(tc.in); out != tc.out {
+				t.Errorf("failed; expected '%v', got '%v'", tc.out, out)
+			}
+		})
+	}
+}
+
+func Test_RegisterActivation(t *testing.T) {
+	double := NeuronFunctions{
+		Activation: func(x float64) float64 { return 2 * x },
+		Derivative: func(float64) float64 { return 2 },
+	}
+
+	t.Run("register", func(t *testing.T) {
+		if !slices.Contains(Activations(), "test_double") { // registry outlives repeated test runs
+			err := RegisterActivation("test_double", double)
+			if err != nil {
+				t.Error(err)
+			}
+		}
+
+		f, err := WithActivation("test_double")
+		if err != nil {
+			t.Error(err)
+		}
+		n := (*f)(&Neuron{})
+		if n.Functions.Name != "test_double" || n.Functions.Activation(2) != 4 {
+			t.Error("failed to use registered activation")
+		}
+
+		if !slices.Contains(Activations(), "test_double") {
+			t.Error("failed to list registered activation")
+		}
+	})
+
+	t.Run("list parameterized", func(t *testing.T) {
+		for _, name := range []string{"leaky_relu(slope)", "elu(alpha)"} {
+			if !slices.Contains(Activations(), name) {
+				t.Errorf("expected '%v' to be listed", name)
+			}
+		}
+	})
+
+	t.Run("serialize", func(t *testing.T) {
+		f, err := WithActivation("test_double")
+		if err != nil {
+			t.Fatal(err)
+		}
+		nn := NewNeuralNet(f, 1, 1)
+		content, err := nn.Serialize()
+		if err != nil {
+			t.Error(err)
+		}
+		nn, err = NewNeuralNetFromSerialized(nil, content)
+		if err != nil {
+			t.Error(err)
+		}
+		if nn[1][0].Functions.Name != "test_double" || nn[1][0].Functions.Activation(1) != 2 {
+			t.Error("failed to restore registered activation")
+		}
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		for _, name := range []string{"test_double", "relu", "elu"} {
+			if err := RegisterActivation(name, double); err == nil {
+				t.Errorf("expected error for '%v', didn't get one", name)
+			}
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if err := RegisterActivation("", double); err == nil {
+			t.Error("expected error for empty name, didn't get one")
+		}
+		if err := RegisterActivation("test_incomplete", NeuronFunctions{}); err == nil {
+			t.Error("expected error for missing functions, didn't get one")
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		f, err := WithActivation("test_unknown")
+		if f != nil || err == nil {
+			t.Error("expected error, didn't get one")
+		}
+	})
+
+	t.Run("parameterized", func(t *testing.T) {
+		f, err := WithActivation("leaky_relu(0.05)")
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := (*f)(&Neuron{})
+		if n.Functions.Name != "leaky_relu(0.05)" || n.Functions.Activation(-2) != -.1 {
+			t.Error("failed to resolve parameterized activation")
+		}
+
+		nn := NewNeuralNet(WithElu(.5), 1, 1)
+		content, err := nn.Serialize()
+		if err != nil {
+			t.Error(err)
+		}
+		nn, err = NewNeuralNetFromSerialized(nil, content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nn[1][0].Functions.Name != "elu(0.5)" || nn[1][0].Functions.Activation(-1) != Elu(-1, .5) {
+			t.Error("failed to restore parameterized activation")
+		}
+
+		for _, name := range []string{"leaky_relu(x)", "leaky_relu(0.1", "leaky_relu", "nope(1)"} {
+			if _, err := WithActivation(name); err == nil {
+				t.Errorf("expected error for '%v', didn't get one", name)
+			}
+		}
+	})
+
+	t.Run("built-in", func(t *testing.T) {
+		for _, name := range []string{"gelu", "linear", "relu", "selu", "sigmoid", "softmax", "softplus", "softsign", "swish", "tanh"} {
+			f, err := WithActivation(name)
+			if err != nil {
+				t.Error(err)
+				continue
+			}
+			if n := (*f)(&Neuron{}); n.Functions.Name != name {
+				t.Errorf("expected '%v', got '%v'", name, n.Functions.Name)
+			}
+		}
+	})
+}
+
 func Test_CallHelper(t *testing.T) {
 	t.Run("sigmoid", func(t *testing.T) {
 		f := WithSigmoid()
@@ -144,4 +449,17 @@ func Test_CallHelper(t *testing.T) {
 			t.Error("failed to use derivative tanh")
 		}
 	})
+
+	t.Run("softmax", func(t *testing.T) {
+		f := WithSoftmax()
+		n := &Neuron{}
+		n = (*f)(n)
+		if n.Functions.Layer == nil {
+			t.Error("failed to use layer activation softmax")
+		}
+		r := n.Functions.Activation(4)
+		if r != 4 {
+			t.Error("failed to keep logit for softmax")
+		}
+	})
 }
@@ -0,0 +1,110 @@
+package qndnn
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+// Initializer draws the initial weight of an input; fanIn is the size of the
+// previous layer, fanOut the size of the layer the weight belongs to
+type Initializer func(r *rand.Rand, fanIn int, fanOut int) float64
+
+// UniformInitializer draws weights from [0, 1); used if no initializer is set
+func UniformInitializer() Initializer {
+	return func(r *rand.Rand, _ int, _ int) float64 {
+		return r.Float64()
+	}
+}
+
+func uniform(r *rand.Rand, limit float64) float64 {
+	return (r.Float64()*2 - 1) * limit
+}
+
+func normal(r *rand.Rand, stddev float64) float64 {
+	return r.NormFloat64() * stddev
+}
+
+// XavierUniformInitializer (Glorot) suits sigmoid and tanh layers
+func XavierUniformInitializer() Initializer {
+	return func(r *rand.Rand, fanIn int, fanOut int) float64 {
+		return uniform(r, math.Sqrt(6/float64(fanIn+fanOut)))
+	}
+}
+
+func XavierNormalInitializer() Initializer {
+	return func(r *rand.Rand, fanIn int, fanOut int) float64 {
+		return normal(r, math.Sqrt(2/float64(fanIn+fanOut)))
+	}
+}
+
+// HeUniformInitializer suits relu layers and its variants
+func HeUniformInitializer() Initializer {
+	return func(r *rand.Rand, fanIn int, _ int) float64 {
+		return uniform(r, math.Sqrt(6/float64(fanIn)))
+	}
+}
+
+func HeNormalInitializer() Initializer {
+	return func(r *rand.Rand, fanIn int, _ int) float64 {
+		return normal(r, math.Sqrt(2/float64(fanIn)))
+	}
+}
+
+// LeCunUniformInitializer suits selu layers
+func LeCunUniformInitializer() Initializer {
+	return func(r *rand.Rand, fanIn int, _ int) float64 {
+		return uniform(r, math.Sqrt(3/float64(fanIn)))
+	}
+}
+
+func LeCunNormalInitializer() Initializer {
+	return func(r *rand.Rand, fanIn int, _ int) float64 {
+		return normal(r, math.Sqrt(1/float64(fanIn)))
+	}
+}
+
+type NetConfig struct {
+	Rand        *rand.Rand  // global random generator if nil
+	Initializer Initializer // UniformInitializer if nil
+	ZeroBias    bool        // biases are drawn from [0, 1) otherwise
+
+	FallbackOnly bool // see WithFallbackActivation
+}
+
+type NetOption func(*NetConfig)
+
+// WithRand draws all weights and biases from r; the same seed creates the
+// same network
+func WithRand(r *rand.Rand) NetOption {
+	return func(c *NetConfig) {
+		c.Rand = r
+	}
+}
+
+func WithInitializer(i Initializer) NetOption {
+	return func(c *NetConfig) {
+		c.Initializer = i
+	}
+}
+
+func WithZeroBias() NetOption {
+	return func(c *NetConfig) {
+		c.ZeroBias = true
+	}
+}
+
+// WithFallbackActivation makes NewNeuralNetFromSerialized use the requested
+// activation only for neurons stored without one, e.g. by older versions,
+// instead of rejecting networks stored with another activation
+func WithFallbackActivation() NetOption {
+	return func(c *NetConfig) {
+		c.FallbackOnly = true
+	}
+}
+
+// globalSource draws from the global random generator
+type globalSource struct{}
+
+func (globalSource) Uint64() uint64 {
+	return rand.Uint64()
+}
@@ -0,0 +1,118 @@
+package qndnn
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+func Test_Initializer(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		i      Initializer
+		limit  float64 // bound of uniform initializers
+		stddev float64 // of normal initializers
+	}{
+		{"xavier uniform", XavierUniformInitializer(), math.Sqrt(6.0 / 30), 0},
+		{"xavier normal", XavierNormalInitializer(), 0, math.Sqrt(2.0 / 30)},
+		{"he uniform", HeUniformInitializer(), math.Sqrt(6.0 / 20), 0},
+		{"he normal", HeNormalInitializer(), 0, math.Sqrt(2.0 / 20)},
+		{"lecun uniform", LeCunUniformInitializer(), math.Sqrt(3.0 / 20), 0},
+		{"lecun normal", LeCunNormalInitializer(), 0, math.Sqrt(1.0 / 20)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := rand.New(rand.NewPCG(1, 2))
+			n := 20000
+			sum, squared := 0.0, 0.0
+			for range n {
+				w := tc.i(r, 20, 10)
+				if tc.limit > 0 && math.Abs(w) > tc.limit {
+					t.Fatalf("weight '%v' exceeds limit '%v'", w, tc.limit)
+				}
+				sum += w
+				squared += w * w
+			}
+
+			mean := sum / float64(n)
+			stddev := math.Sqrt(squared/float64(n) - mean*mean)
+			expected := tc.stddev
+			if tc.limit > 0 {
+				expected = tc.limit / math.Sqrt(3) // of uniform distribution
+			}
+			if math.Abs(mean) > 0.01 || math.Abs(stddev-expected) > 0.01 {
+				t.Errorf("unexpected distribution; mean '%v', stddev '%v' (expected '%v')", mean, stddev, expected)
+			}
+		})
+	}
+
+	t.Run("uniform", func(t *testing.T) {
+		r := rand.New(rand.NewPCG(1, 2))
+		for range 1000 {
+			if w := UniformInitializer()(r, 2, 2); w < 0 || w >= 1 {
+				t.Fatalf("weight '%v' not in [0, 1)", w)
+			}
+		}
+	})
+}
+
+func Test_NewNetworkWithOptions(t *testing.T) {
+	specs := []LayerSpec{
+		{Size: 3},
+		{Size: 4, Activation: WithRelu()},
+		{Size: 2},
+	}
+
+	t.Run("seeded", func(t *testing.T) {
+		serialize := func(seed uint64) string {
+			nn := NewNeuralNetFromSpec(specs, WithRand(rand.New(rand.NewPCG(seed, seed))), WithInitializer(HeNormalInitializer()))
+			content, err := nn.Serialize()
+			if err != nil {
+				t.Error(err)
+			}
+			return content
+		}
+
+		if serialize(42) != serialize(42) {
+			t.Error("expected identical networks for the same seed")
+		}
+
+		if serialize(42) == serialize(43) {
+			t.Error("expected different networks for different seeds")
+		}
+	})
+
+	t.Run("zero bias", func(t *testing.T) {
+		nn := NewNeuralNetFromSpec(specs, WithZeroBias(), WithInitializer(XavierUniformInitializer()))
+		negative := false
+		for _, l := range nn {
+			for _, n := range l {
+				if n.Bias != 0 {
+					t.Error("expected zero bias")
+				}
+				for _, i := range n.Inputs {
+					negative = negative || i.Weight < 0
+				}
+			}
+		}
+
+		if !negative {
+			t.Error("expected weights to be centered around zero")
+		}
+	})
+
+	t.Run("default", func(t *testing.T) {
+		nn := NewNeuralNetFromSpec(specs)
+		for _, l := range nn[1:] {
+			for _, n := range l {
+				if n.Bias < 0 || n.Bias >= 1 {
+					t.Errorf("bias '%v' not in [0, 1)", n.Bias)
+				}
+				for _, i := range n.Inputs {
+					if i.Weight < 0 || i.Weight >= 1 {
+						t.Errorf("weight '%v' not in [0, 1)", i.Weight)
+					}
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package qndnn
+
+import "math"
+
+// Loss measures the error of a single output against its expectation; the
+// gradient is the derivative of the value with respect to the output
+type Loss interface {
+	Value(out float64, expected float64) float64
+	Gradient(out float64, expected float64) float64
+}
+
+type squaredError struct{}
+
+func (squaredError) Value(out float64, expected float64) float64 {
+	return 0.5 * math.Pow(out-expected, 2)
+}
+
+func (squaredError) Gradient(out float64, expected float64) float64 {
+	return out - expected
+}
+
+func SquaredError() Loss {
+	return squaredError{}
+}
+
+type meanAbsoluteError struct{}
+
+func (meanAbsoluteError) Value(out float64, expected float64) float64 {
+	return math.Abs(out - expected)
+}
+
+func (meanAbsoluteError) Gradient(out float64, expected float64) float64 {
+	switch {
+	case out > expected:
+		return 1
+	case out < expected:
+		return -1
+	default:
+		return 0
+	}
+}
+
+func MeanAbsoluteError() Loss {
+	return meanAbsoluteError{}
+}
+
+type huber struct {
+	delta float64
+}
+
+func (h huber) Value(out float64, expected float64) float64 {
+	d := math.Abs(out - expected)
+	if d <= h.delta {
+		return 0.5 * d * d
+	}
+	return h.delta * (d - 0.5*h.delta)
+}
+
+func (h huber) Gradient(out float64, expected float64) float64 {
+	d := out - expected
+	return math.Max(-h.delta, math.Min(h.delta, d))
+}
+
+// Huber is quadratic for errors up to delta and linear beyond, so outliers in
+// noisy data don't dominate training
+func Huber(delta float64) Loss {
+	return huber{delta: delta}
+}
+
+// probabilityEpsilon keeps probabilities away from 0 and 1 for the logarithm
+const probabilityEpsilon = 1e-12
+
+func clampProbability(p float64) float64 {
+	return math.Max(probabilityEpsilon, math.Min(1-probabilityEpsilon, p))
+}
+
+type binaryCrossEntropy struct{}
+
+func (binaryCrossEntropy) Value(out float64, expected float64) float64 {
+	p := clampProbability(out)
+	return -(expected*math.Log(p) + (1-expected)*math.Log(1-p))
+}
+
+func (binaryCrossEntropy) Gradient(out float64, expected float64) float64 {
+	p := clampProbability(out)
+	return (p - expected) / (p * (1 - p))
+}
+
+// BinaryCrossEntropy expects outputs in (0, 1), e.g. from sigmoid neurons
+func BinaryCrossEntropy() Loss {
+	return binaryCrossEntropy{}
+}
+
+type categoricalCrossEntropy struct{}
+
+func (categoricalCrossEntropy) Value(out float64, expected float64) float64 {
+	return -expected * math.Log(clampProbability(out))
+}
+
+func (categoricalCrossEntropy) Gradient(out float64, expected float64) float64 {
+	return -expected / clampProbability(out)
+}
+
+// CategoricalCrossEntropy expects outputs forming a probability distribution
+// and one-hot (or otherwise summing to 1) expectations
+func CategoricalCrossEntropy() Loss {
+	return categoricalCrossEntropy{}
+}
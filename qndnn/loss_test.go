@@ -0,0 +1,116 @@
+package qndnn
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func Test_Loss(t *testing.T) {
+	for _, tc := range []struct {
+		name                   string
+		l                      Loss
+		out, expected, v, grad float64
+	}{
+		{"squared error", SquaredError(), .8, .5, 0.045000000000000005, 0.30000000000000004},
+		{"mean absolute error", MeanAbsoluteError(), .2, .5, .3, -1},
+		{"mean absolute error exact", MeanAbsoluteError(), .5, .5, 0, 0},
+		{"huber quadratic", Huber(1), .8, .5, 0.045000000000000005, 0.30000000000000004},
+		{"huber linear", Huber(1), 3, .5, 2, 1},
+		{"binary cross entropy", BinaryCrossEntropy(), .8, 1, -math.Log(.8), -1 / .8},
+		{"categorical cross entropy", CategoricalCrossEntropy(), .25, 1, -math.Log(.25), -4},
+		{"categorical cross entropy other class", CategoricalCrossEntropy(), .25, 0, 0, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			v := tc.l.Value(tc.out, tc.expected)
+			if math.Abs(v-tc.v) > 1e-12 {
+				t.Errorf("failed value; expected '%v', got '%v'", tc.v, v)
+			}
+
+			g := tc.l.Gradient(tc.out, tc.expected)
+			if math.Abs(g-tc.grad) > 1e-12 {
+				t.Errorf("failed gradient; expected '%v', got '%v'", tc.grad, g)
+			}
+		})
+	}
+
+	t.Run("numerical gradient", func(t *testing.T) {
+		h := 1e-6
+		for _, l := range []Loss{SquaredError(), Huber(.5), BinaryCrossEntropy(), CategoricalCrossEntropy()} {
+			for _, tc := range []struct{ out, expected float64 }{{.3, 1}, {.6, 0}, {.9, .25}} {
+				numerical := (l.Value(tc.out+h, tc.expected) - l.Value(tc.out-h, tc.expected)) / (2 * h)
+				if g := l.Gradient(tc.out, tc.expected); math.Abs(g-numerical) > 1e-6 {
+					t.Errorf("%T at %v/%v: expected '%v', got '%v'", l, tc.out, tc.expected, numerical, g)
+				}
+			}
+		}
+	})
+
+	t.Run("binary cross entropy saturated", func(t *testing.T) {
+		l := BinaryCrossEntropy()
+		for _, out := range []float64{0, 1} {
+			if v := l.Value(out, .5); math.IsInf(v, 0) || math.IsNaN(v) {
+				t.Errorf("expected finite value at %v, got '%v'", out, v)
+			}
+			if g := l.Gradient(out, .5); math.IsInf(g, 0) || math.IsNaN(g) {
+				t.Errorf("expected finite gradient at %v, got '%v'", out, g)
+			}
+		}
+	})
+}
+
+func Test_TrainWithLoss(t *testing.T) {
+	expectations := []Expectations{
+		{[]float64{0, 0}, []float64{0}},
+		{[]float64{1, 1}, []float64{1}},
+	}
+
+	total := func(nn NeuralNetwork, l Loss) float64 {
+		v := 0.0
+		for _, e := range expectations {
+			out, err := nn.Output(e.Input)
+			if err != nil {
+				t.Error(err)
+			}
+			v += l.Value(out[0], e.Output[0])
+		}
+		return v
+	}
+
+	for _, l := range []Loss{SquaredError(), MeanAbsoluteError(), Huber(.1), BinaryCrossEntropy()} {
+		t.Run(fmt.Sprintf("%T reported", l), func(t *testing.T) {
+			nn := NewNeuralNet(nil, 2, 3, 1)
+			out, err := nn.Output(expectations[1].Input)
+			if err != nil {
+				t.Error(err)
+			}
+
+			var reported []float64
+			rounds := RoundStrategy(1)
+			_, err = nn.Train(expectations[1:], .5, func(s TrainingState) bool {
+				reported = s.Errors
+				return rounds(s)
+			}, WithLoss(l))
+			if err != nil {
+				t.Error(err)
+			}
+
+			if v := l.Value(out[0], expectations[1].Output[0]); len(reported) != 1 || v != reported[0] {
+				t.Errorf("strategy didn't receive loss value; expected '%v', got '%v'", v, reported)
+			}
+		})
+
+		t.Run(fmt.Sprintf("%T decreasing", l), func(t *testing.T) {
+			nn := NewNeuralNet(nil, 2, 3, 1)
+			before := total(nn, l)
+			_, err := nn.Train(expectations, .5, RoundStrategy(50), WithLoss(l))
+			if err != nil {
+				t.Error(err)
+			}
+
+			if after := total(nn, l); after >= before {
+				t.Errorf("loss didn't decrease; before '%v', after '%v'", before, after)
+			}
+		})
+	}
+}
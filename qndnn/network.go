@@ -1,12 +1,15 @@
 package qndnn
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"math"
 	"math/rand/v2"
+	"runtime"
+	"slices"
 	"sync"
 	"time"
 )
@@ -15,8 +18,8 @@ type Input struct {
 	N      *Neuron `json:"-"`
 	Weight float64 `json:"weight"`
 
-	changeLock    sync.Mutex
-	PendingChange float64 `json:"-"`
+	PendingChange float64         `json:"-"`
+	Optimizer     *OptimizerState `json:"optimizer,omitempty"`
 }
 
 func (i *Input) Result() float64 {
@@ -31,26 +34,24 @@ func (i *Input) UpdateWeight() {
 type Neuron struct {
 	Inputs    []*Input        `json:"inputs"`
 	Bias      float64         `json:"bias"`
-	Functions NeuronFunctions `json:"-"`
+	Functions NeuronFunctions `json:"activation"`
 
 	Preset *float64 `json:"preset"` // mostly used for input definition
+
+	PendingBiasChange float64         `json:"-"`
+	BiasOptimizer     *OptimizerState `json:"bias_optimizer,omitempty"`
+}
+
+func (n *Neuron) UpdateBias() {
+	n.Bias -= n.PendingBiasChange
+	n.PendingBiasChange = 0
 }
 
 func (n *Neuron) Input() float64 {
-	var mu sync.Mutex
 	v := 0.0
-	var q sync.WaitGroup
-	q.Add(len(n.Inputs))
-	for _, i := range n.Inputs {
-		go func(i *Input) {
-			defer q.Done()
-			r := i.Result()
-			mu.Lock()
-			v += r
-			mu.Unlock()
-		}(i)
-	}
-	q.Wait()
+	for _, i := range n.Inputs { // summed in order of inputs, so the result is always the same
+		v += i.Result()
+	}
 	v += n.Bias
 	return v
 }
@@ -63,76 +64,246 @@ func (n *Neuron) Value() float64 {
 	return n.Functions.Activation(n.Input())
 }
 
-func (n *Neuron) Learn(delta float64, weight float64, learningRate float64) {
-	derivative := n.Functions.Derivative(n.Input()) // derivative of output
-	d := derivative * weight * delta
+type NeuralNetwork [][]*Neuron
+
+// pass holds the cached pre-activations, activations and deltas of every layer
+// for a single forward/backward run, so no neuron is evaluated more than once
+type pass struct {
+	pre        [][]float64
+	act        [][]float64
+	delta      [][]float64
+	derivative [][]float64 // scratch space of the backward pass
+
+	workers int // goroutines sharing the neurons of a layer
+}
+
+func newPass(sizes ...int) *pass {
+	p := &pass{
+		pre:        make([][]float64, len(sizes)),
+		act:        make([][]float64, len(sizes)),
+		delta:      make([][]float64, len(sizes)),
+		derivative: make([][]float64, len(sizes)),
+
+		workers: 1,
+	}
+	for idx, size := range sizes {
+		p.pre[idx] = make([]float64, size)
+		p.act[idx] = make([]float64, size)
+		p.delta[idx] = make([]float64, size)
+		p.derivative[idx] = make([]float64, size)
+	}
+	return p
+}
+
+// parallel splits [0, n) into contiguous chunks handled by up to workers
+// goroutines; a single worker runs f on the calling goroutine
+func parallel(workers int, n int, f func(from int, to int)) {
+	if workers <= 1 || n <= 1 {
+		f(0, n)
+		return
+	}
 
+	size := (n + workers - 1) / workers
 	var wg sync.WaitGroup
-	wg.Add(len(n.Inputs))
-	for _, i := range n.Inputs {
-		go func(i *Input) {
+	for from := 0; from < n; from += size {
+		wg.Add(1)
+		go func() {
 			defer wg.Done()
-
-			pc := learningRate * d * i.N.Value()
-			i.changeLock.Lock()
-			i.PendingChange += pc // track pending change; to apply after back propagation is done
-			i.changeLock.Unlock()
-			i.N.Learn(d, i.Weight, learningRate) // pass along weighted expectation + learning rate
-		}(i)
+			f(from, min(from+size, n))
+		}()
 	}
 	wg.Wait()
 }
 
-type NeuralNetwork [][]*Neuron
-
+// Output evaluates the network for the given input; it reads the weights of the
+// neurons in place, without compiling an Inferencer, and doesn't modify the
+// network, so it is safe to be called from multiple goroutines at once
 func (nn NeuralNetwork) Output(in []float64) ([]float64, error) {
 	if len(in) != len(nn[0]) {
 		return nil, fmt.Errorf("input didn't match first layer; expected len '%v', got '%v'", len(nn[0]), len(in))
 	}
 
-	for idx, n := range nn[0] {
-		n.Preset = &in[idx]
+	act := slices.Clone(in)
+	for idx := 1; idx < len(nn); idx++ {
+		prev := act
+		pre := make([]float64, len(nn[idx]))
+		act = make([]float64, len(nn[idx]))
+		for nidx, n := range nn[idx] {
+			v := 0.0
+			for iidx, i := range n.Inputs { // summed in order, like the dense layers
+				v += prev[iidx] * i.Weight
+			}
+			v += n.Bias
+			pre[nidx] = v
+			act[nidx] = n.Functions.Activation(v)
+		}
+
+		if len(nn[idx]) > 0 && nn[idx][0].Functions.Layer != nil {
+			nn[idx][0].Functions.Layer.Activation(pre, act)
+		}
 	}
+	return act, nil
+}
 
-	var result []float64
-	last := nn[len(nn)-1]
-	for _, o := range last {
-		result = append(result, o.Value())
+// OutputBatch evaluates all rows with a bounded number of workers; results are
+// returned in order of the input rows
+func (nn NeuralNetwork) OutputBatch(in [][]float64) ([][]float64, error) {
+	for idx, row := range in {
+		if len(row) != len(nn[0]) {
+			return nil, fmt.Errorf("input #%v didn't match first layer; expected len '%v', got '%v'", idx, len(nn[0]), len(row))
+		}
 	}
+
+	d := nn.Dense() // compiled once, read by all workers
+	workers := min(runtime.GOMAXPROCS(0), len(in))
+	result := make([][]float64, len(in))
+	rows := make(chan int)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for range workers {
+		go func() {
+			defer wg.Done()
+			inf := d.Inferencer()
+			for idx := range rows {
+				result[idx], _ = inf.Output(in[idx]) // dimensions are validated upfront
+			}
+		}()
+	}
+
+	for idx := range in {
+		rows <- idx
+	}
+	close(rows)
+	wg.Wait()
+
 	return result, nil
 }
 
-func NewNeuralNet(neuronCreate *func(*Neuron) *Neuron, layers ...int) NeuralNetwork {
-	var l [][]*Neuron
+// Predict returns the index of the output with the highest value, e.g. the
+// class of a softmax output layer
+func (nn NeuralNetwork) Predict(in []float64) (int, error) {
+	out, err := nn.Output(in)
+	if err != nil {
+		return -1, err
+	}
+	return ArgMax(out), nil
+}
 
-	if neuronCreate == nil {
-		neuronCreate = WithSigmoid()
+// Inferencer is a compiled inference plan; it holds a copy of the weights in
+// contiguous layers and owns all buffers, so repeated calls don't allocate.
+// Changes to the network after compiling aren't seen, obtain a new Inferencer
+// after training. An Inferencer itself must not be shared between goroutines,
+// obtain one per goroutine instead
+type Inferencer struct {
+	d Dense
+	p *pass
+}
+
+func (nn NeuralNetwork) Inferencer() *Inferencer {
+	return nn.Dense().Inferencer()
+}
+
+// Inferencer compiles an inference plan reading the dense layers, which must
+// not change while the plan is in use
+func (d Dense) Inferencer() *Inferencer {
+	return &Inferencer{
+		d: d,
+		p: d.newPass(),
 	}
+}
 
+func (inf *Inferencer) Output(in []float64) ([]float64, error) {
+	out := make([]float64, len(inf.d[len(inf.d)-1].Biases))
+	if err := inf.OutputInto(out, in); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// OutputInto evaluates the network for in and writes the result to dst, which
+// must have the size of the last layer; it doesn't allocate
+func (inf *Inferencer) OutputInto(dst []float64, in []float64) error {
+	d := inf.d
+	if len(in) != len(d[0].Biases) {
+		return fmt.Errorf("input didn't match first layer; expected len '%v', got '%v'", len(d[0].Biases), len(in))
+	}
+	last := len(d) - 1
+	if len(dst) != len(d[last].Biases) {
+		return fmt.Errorf("destination didn't match last layer; expected len '%v', got '%v'", len(d[last].Biases), len(dst))
+	}
+
+	d.forward(inf.p, in)
+	copy(dst, inf.p.act[last])
+	return nil
+}
+
+func NewNeuralNet(neuronCreate *func(*Neuron) *Neuron, layers ...int) NeuralNetwork {
+	specs := make([]LayerSpec, len(layers))
 	for idx, size := range layers {
-		n := make([]*Neuron, size)
+		specs[idx] = LayerSpec{
+			Size:       size,
+			Activation: neuronCreate,
+		}
+	}
+	return NewNeuralNetFromSpec(specs)
+}
+
+// LayerSpec describes a single layer; the activation of the first (input) layer
+// is not used
+type LayerSpec struct {
+	Size       int
+	Activation *func(*Neuron) *Neuron // sigmoid if nil
+}
+
+// NewNeuralNetFromSpec creates a network with an activation per layer, e.g.
+// relu for hidden layers and sigmoid for the output layer
+func NewNeuralNetFromSpec(specs []LayerSpec, opts ...NetOption) NeuralNetwork {
+	c := NetConfig{}
+	for _, o := range opts {
+		o(&c)
+	}
+
+	if c.Rand == nil {
+		c.Rand = rand.New(globalSource{})
+	}
+
+	if c.Initializer == nil {
+		c.Initializer = UniformInitializer()
+	}
+
+	var l [][]*Neuron
+
+	for idx, spec := range specs {
+		neuronCreate := spec.Activation
+		if neuronCreate == nil {
+			neuronCreate = WithSigmoid()
+		}
+
+		n := make([]*Neuron, spec.Size)
 		init := 1.0
-		for nidx, _ := range n {
+		for nidx := range n {
 			a := &Neuron{
 				Bias: 0,
 			}
 			if idx == 0 {
 				a.Preset = &init
 			}
-			if neuronCreate != nil {
-				a = (*neuronCreate)(a)
-			}
-			n[nidx] = a
+			n[nidx] = (*neuronCreate)(a)
 		}
 
 		if idx > 0 {
 			prev := l[idx-1]
 			for _, ne := range n {
-				ne.Bias = rand.Float64()
+				bias := c.Rand.Float64() // drawn regardless; weights don't depend on ZeroBias
+				if c.ZeroBias {
+					bias = 0
+				}
+				ne.Bias = bias
 				for _, p := range prev {
 					in := Input{
 						N:      p,
-						Weight: rand.Float64(),
+						Weight: c.Initializer(c.Rand, len(prev), len(n)),
 					}
 					ne.Inputs = append(ne.Inputs, &in)
 				}
@@ -149,7 +320,106 @@ type Expectations struct {
 	Output []float64
 }
 
-type Strategy func(errs []float64) bool
+type TrainConfig struct {
+	FreezeBiases bool
+	BatchSize    int       // number of expectations averaged per update; 1 (default) is online learning
+	Optimizer    Optimizer // applies the pending changes; nil (default) applies them as they are
+	Loss         Loss      // nil (default) reports plain differences and keeps the original update rule
+
+	RestoreOnDivergence bool
+	RestoreBest         bool           // sets the weights of the epoch with the lowest validation loss once training ends
+	Shuffle             *rand.Rand     // shuffles the expectations every epoch if set
+	Workers             int            // goroutines sharing the neurons of a layer; 1 (default) is sequential
+	Validation          []Expectations // evaluated after every epoch, but never trained on
+	Metrics             map[string]Metric
+}
+
+type TrainOption func(*TrainConfig)
+
+// WithFrozenBiases keeps all biases at their current value during training;
+// this is how networks were trained before biases were learned
+func WithFrozenBiases() TrainOption {
+	return func(c *TrainConfig) {
+		c.FreezeBiases = true
+	}
+}
+
+// WithBatchSize averages the changes of size expectations before applying
+// them; use len(expectations) for full-batch gradient descent
+func WithBatchSize(size int) TrainOption {
+	return func(c *TrainConfig) {
+		c.BatchSize = size
+	}
+}
+
+// WithOptimizer applies pending changes with the optimizer; its per weight
+// state is kept in the network, so pass the same optimizer when resuming
+func WithOptimizer(o Optimizer) TrainOption {
+	return func(c *TrainConfig) {
+		c.Optimizer = o
+	}
+}
+
+// WithValidation evaluates the loss of the held-out expectations after every
+// epoch; strategies find it in TrainingState.ValidationLoss
+func WithValidation(expectations []Expectations) TrainOption {
+	return func(c *TrainConfig) {
+		c.Validation = expectations
+	}
+}
+
+// WithWorkers shares the neurons of each layer between workers goroutines;
+// worthwhile for wide layers only, small networks train fastest sequentially
+func WithWorkers(workers int) TrainOption {
+	return func(c *TrainConfig) {
+		c.Workers = workers
+	}
+}
+
+// WithShuffle trains on the expectations in a different order every epoch,
+// drawn from r
+func WithShuffle(r *rand.Rand) TrainOption {
+	return func(c *TrainConfig) {
+		c.Shuffle = r
+	}
+}
+
+// WithDeterministic shuffles the expectations every epoch, seeded by seed; as
+// all sums are taken in a fixed order, training the same network on the same
+// expectations results in bit-identical weights
+func WithDeterministic(seed uint64) TrainOption {
+	return WithShuffle(rand.New(rand.NewPCG(seed, seed)))
+}
+
+// WithRestoreOnDivergence sets the network back to its last finite weights
+// and biases if training diverges
+func WithRestoreOnDivergence() TrainOption {
+	return func(c *TrainConfig) {
+		c.RestoreOnDivergence = true
+	}
+}
+
+// WithRestoreBest keeps the weights of the epoch with the lowest validation
+// loss and sets them once training ends, e.g. after EarlyStopping; requires
+// WithValidation
+func WithRestoreBest() TrainOption {
+	return func(c *TrainConfig) {
+		c.RestoreBest = true
+	}
+}
+
+// WithLoss trains on the gradient of the loss; the errors passed to the
+// strategy are the loss values per output
+func WithLoss(l Loss) TrainOption {
+	return func(c *TrainConfig) {
+		c.Loss = l
+	}
+}
+
+// Strategy decides whether to train another epoch; strategies keeping state
+// start over once the epoch goes back, so they can be reused for another
+// training run
+type Strategy func(s TrainingState) bool
 
 func RoundStrategy(rounds int) Strategy {
 	if rounds < 0 {
@@ -157,9 +427,13 @@ func RoundStrategy(rounds int) Strategy {
 	}
 
 	counter := 0
-	return func(_ []float64) bool {
+	return func(s TrainingState) bool {
+		if s.Start {
+			counter = 0
+		}
+
 		if counter == rounds {
-			return false
+			return s.Stop(StopRounds)
 		} else {
 			counter++
 			return true
@@ -167,22 +441,65 @@ func RoundStrategy(rounds int) Strategy {
 	}
 }
 
+// EarlyStopping stops once the validation loss (the training loss without
+// validation set) didn't improve by more than minDelta for patience epochs;
+// combine with WithRestoreBest to end up with the best weights
+func EarlyStopping(patience int, minDelta float64) Strategy {
+	best := math.Inf(1)
+	waited := 0
+	return func(s TrainingState) bool {
+		if s.Start {
+			best = math.Inf(1)
+			waited = 0
+		}
+
+		if s.Epoch == 0 {
+			return true // nothing measured yet
+		}
+
+		loss := s.Loss
+		if s.Validated {
+			loss = s.ValidationLoss
+		}
+
+		if loss < best-minDelta {
+			best = loss
+			waited = 0
+			return true
+		}
+
+		waited++
+		if waited >= patience {
+			return s.Stop(StopEarly)
+		}
+		return true
+	}
+}
+
 func ThresholdStrategy(errorThreshold float64, stopAfter time.Duration) Strategy {
 	start := time.Now()
 	end := start.Add(stopAfter)
-	return func(errs []float64) bool {
+	return func(s TrainingState) bool {
+		if s.Start {
+			end = time.Now().Add(stopAfter)
+		}
+
 		if stopAfter > 0 && time.Now().After(end) {
-			return false
+			return s.Stop(StopTimeout)
 		}
 
 		cumulated := 0.0
-		for _, err := range errs {
+		for _, err := range s.Errors {
 			cumulated += math.Abs(err)
 		}
 
-		if len(errs) > 0 && // this is important, because on first run we don't have errors yet; so it would stop immediately
+		if math.IsNaN(cumulated) {
+			return s.Stop(StopDiverged) // would never reach the threshold
+		}
+
+		if len(s.Errors) > 0 && // this is important, because on first run we don't have errors yet; so it would stop immediately
 			cumulated <= errorThreshold {
-			return false
+			return s.Stop(StopThreshold)
 		} else {
 			return true
 		}
@@ -191,14 +508,21 @@ func ThresholdStrategy(errorThreshold float64, stopAfter time.Duration) Strategy
 
 func WithLoggingStrategy(out io.Writer, strategy Strategy) Strategy {
 	f := strategy
-	return func(errs []float64) bool {
+	return func(s TrainingState) bool {
 		cum := 0.0
-		for _, err := range errs {
+		for _, err := range s.Errors {
 			cum += math.Abs(err)
 		}
 		// we ignore errors, since this strategy is rather for user info
-		_, _ = fmt.Fprintf(out, "%s – cumulated error: %.10f\n", time.Now().Format(time.DateTime), cum)
-		return f(errs)
+		_, _ = fmt.Fprintf(
+			out,
+			"%s – epoch %v, loss: %.10f, cumulated error: %.10f\n",
+			time.Now().Format(time.DateTime),
+			s.Epoch,
+			s.Loss,
+			cum,
+		)
+		return f(s)
 	}
 }
 
@@ -206,10 +530,45 @@ func (nn NeuralNetwork) Train(
 	expectations []Expectations,
 	learningRate float64,
 	strategy Strategy,
-) error {
-	for _, e := range expectations {
+	opts ...TrainOption,
+) (*TrainResult, error) {
+	return nn.TrainContext(context.Background(), expectations, learningRate, strategy, opts...)
+}
+
+// TrainContext trains like Train, but stops as soon as ctx is done; it is
+// checked between expectations, the changes of an incomplete batch are
+// discarded, so the network holds the weights of the last applied update.
+// Returns ctx.Err() on cancellation
+func (nn NeuralNetwork) TrainContext(
+	ctx context.Context,
+	expectations []Expectations,
+	learningRate float64,
+	strategy Strategy,
+	opts ...TrainOption,
+) (*TrainResult, error) {
+	c := TrainConfig{
+		BatchSize: 1,
+		Workers:   1,
+	}
+	for _, o := range opts {
+		o(&c)
+	}
+
+	if c.BatchSize < 1 {
+		return nil, fmt.Errorf("batch size must be at least 1, got '%v'", c.BatchSize)
+	}
+
+	if c.Workers < 1 {
+		return nil, fmt.Errorf("workers must be at least 1, got '%v'", c.Workers)
+	}
+
+	if c.RestoreBest && len(c.Validation) == 0 {
+		return nil, fmt.Errorf("restoring the best weights requires a validation set")
+	}
+
+	for _, e := range slices.Concat(expectations, c.Validation) {
 		if len(nn[0]) != len(e.Input) {
-			return fmt.Errorf(
+			return nil, fmt.Errorf(
 				"input doesn't match first layer (want len '%v', got len '%v')",
 				len(nn[0]),
 				len(e.Input),
@@ -217,7 +576,7 @@ func (nn NeuralNetwork) Train(
 		}
 
 		if len(nn[len(nn)-1]) != len(e.Output) {
-			return fmt.Errorf(
+			return nil, fmt.Errorf(
 				"expected output doesn't match last layer (want len '%v', got len '%v')",
 				len(nn[len(nn)-1]),
 				len(e.Output),
@@ -225,40 +584,147 @@ func (nn NeuralNetwork) Train(
 		}
 	}
 
-	var errs []float64
+	// train on contiguous layers; the network is kept in sync for the strategy
+	// and once training ends
+	d := nn.Dense()
+	defer d.CopyTo(nn)
+
+	p := d.newPass()
+	p.workers = c.Workers
+	epoch := 0
+	state := TrainingState{LearningRate: learningRate, Start: true}
+	losses := newEpochLoss(len(nn[len(nn)-1]))
+	start := time.Now()
+
+	var best snapshot // parameters of the epoch with the lowest validation loss
+	bestLoss := math.Inf(1)
+
+	result := &TrainResult{}
+	finish := func(reason StopReason) *TrainResult {
+		if c.RestoreBest && result.BestEpoch > 0 && reason != StopDiverged {
+			d.discard()
+			best.restore(d)
+		}
+		result.Reason = reason
+		result.Elapsed = time.Since(start)
+		return result
+	}
+
+	var last snapshot // last finite parameters
+	if c.RestoreOnDivergence {
+		last.take(d)
+	}
+
+	diverged := func(err *DivergedError) error {
+		d.discard()
+		if c.RestoreOnDivergence {
+			last.restore(d)
+			err.Restored = true
+		}
+		return err
+	}
+
+	update := func(pending int) *DivergedError {
+		d.average(pending)
+		d.apply(c, learningRate) // apply all pending weight changes
+		if err := d.checkParameters(epoch); err != nil {
+			return err
+		}
+		if c.RestoreOnDivergence {
+			last.take(d)
+		}
+		state.Step++
+		return nil
+	}
+
+	order := make([]int, len(expectations))
+	for idx := range order {
+		order[idx] = idx
+	}
+
+	cancelled := func() bool {
+		select {
+		case <-ctx.Done():
+			d.discard()
+			return true
+		default:
+			return false
+		}
+	}
+
 	for {
+		if cancelled() {
+			return finish(StopCancelled), ctx.Err()
+		}
+
 		// based on strategy, abort or continue
-		if !strategy(errs) {
-			return nil
+		d.CopyTo(nn)
+		state.Elapsed = time.Since(start)
+		reason := StopStrategy
+		state.reason = &reason
+		if !strategy(state) {
+			return finish(reason), nil
 		}
+		state.Start = false
 
-		for _, e := range expectations {
-			errs = []float64{}
-			// set input
-			for idx, i := range e.Input {
-				nn[0][idx].Preset = &i
+		if c.Shuffle != nil {
+			c.Shuffle.Shuffle(len(order), func(i, j int) {
+				order[i], order[j] = order[j], order[i]
+			})
+		}
+
+		pending := 0
+		for _, idx := range order {
+			if cancelled() {
+				return finish(StopCancelled), ctx.Err()
 			}
 
-			// iterate through all output nodes, comparing result with expectation
-			for idx, n := range nn[len(nn)-1] {
-				in := n.Input()
-				out := n.Value()
-				expected := e.Output[idx]
-				err := out - expected
-				delta := err * n.Functions.Derivative(in)
-				errs = append(errs, err)
+			e := expectations[idx]
+			d.forward(p, e.Input)
+			errs := d.outputDeltas(p, c, e.Output) // compare result with expectation
+			losses.add(c, errs, p.act[len(d)-1], e.Output)
+			d.backward(p, learningRate, !c.FreezeBiases)
+			if err := p.check(errs, epoch); err != nil {
+				return finish(StopDiverged), diverged(err)
+			}
+			pending++
 
-				n.Learn(delta, 1.0, learningRate) // start learning for all recursive; delta is taken full
+			if pending == c.BatchSize {
+				if err := update(pending); err != nil {
+					return finish(StopDiverged), diverged(err)
+				}
+				pending = 0
 			}
+		}
 
-			nn.Update() // apply all pending weight changes
+		if pending > 0 { // apply the remainder of an incomplete batch
+			if err := update(pending); err != nil {
+				return finish(StopDiverged), diverged(err)
+			}
 		}
+		epoch++
+
+		state.Epoch = epoch
+		state.reason = nil
+		losses.update(&state)
+		if len(c.Validation) > 0 {
+			state.Validated = true
+			state.ValidationLoss, state.ValidationMetrics = d.evaluate(p, c, c.Validation)
+			if c.RestoreBest && state.ValidationLoss < bestLoss {
+				bestLoss = state.ValidationLoss
+				best.take(d)
+				result.BestEpoch = epoch
+			}
+		}
+		state.Elapsed = time.Since(start)
+		result.Epochs = append(result.Epochs, state)
 	}
 }
 
 func (nn NeuralNetwork) Update() {
 	for _, l := range nn {
 		for _, n := range l {
+			n.UpdateBias()
 			for _, i := range n.Inputs {
 				i.UpdateWeight()
 			}
@@ -267,6 +733,18 @@ func (nn NeuralNetwork) Update() {
 }
 
 func (nn NeuralNetwork) Serialize() (string, error) {
+	for idx, l := range nn[1:] { // input layer isn't activated
+		for nidx, n := range l {
+			if n.Functions.Activation != nil && n.Functions.Name == "" {
+				return "", fmt.Errorf(
+					"activation of neuron #%v in layer #%v has no name; register it with RegisterActivation and use WithActivation to create the network",
+					nidx,
+					idx+1,
+				)
+			}
+		}
+	}
+
 	out, err := json.Marshal(nn)
 	if err != nil {
 		return "", err
@@ -274,25 +752,43 @@ func (nn NeuralNetwork) Serialize() (string, error) {
 	return base64.StdEncoding.EncodeToString(out), nil
 }
 
-func NewNeuralNetFromSerialized(neuronCreate *func(*Neuron) *Neuron, serialized string) (NeuralNetwork, error) {
+func NewNeuralNetFromSerialized(neuronCreate *func(*Neuron) *Neuron, serialized string, opts ...NetOption) (NeuralNetwork, error) {
+	c := NetConfig{}
+	for _, o := range opts {
+		o(&c)
+	}
+
 	content, err := base64.StdEncoding.DecodeString(serialized)
 	if err != nil {
 		return nil, err
 	}
 
-	if neuronCreate == nil {
-		neuronCreate = WithSigmoid()
-	}
-
 	net := NeuralNetwork{}
 	err = json.Unmarshal(content, &net)
 	if err != nil {
 		return nil, err
 	}
 
+	fallback := neuronCreate
+	if fallback == nil {
+		fallback = WithSigmoid()
+	}
+	requested := (*fallback)(&Neuron{}).Functions.Name
+
 	for idx, l := range net {
-		for _, n := range l {
-			n = (*neuronCreate)(n)
+		for nidx, n := range l {
+			switch {
+			case n.Functions.Name == "": // stored without activation
+				(*fallback)(n)
+			case neuronCreate != nil && !c.FallbackOnly && idx > 0 && n.Functions.Name != requested: // input layer isn't activated
+				return nil, fmt.Errorf(
+					"activation '%v' contradicts stored activation '%v' (layer #%v, neuron #%v)",
+					requested,
+					n.Functions.Name,
+					idx,
+					nidx,
+				)
+			}
 		}
 
 		if idx == 0 {
@@ -2,8 +2,13 @@ package qndnn
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
+	"errors"
+	"fmt"
 	"math"
+	"math/rand/v2"
+	"sync"
 	"testing"
 	"time"
 )
@@ -63,6 +68,47 @@ func Test_NewNetwork(t *testing.T) {
 	}
 }
 
+func Test_NewNetworkFromSpec(t *testing.T) {
+	nn := NewNeuralNetFromSpec([]LayerSpec{
+		{Size: 2},
+		{Size: 3, Activation: WithRelu()},
+		{Size: 3, Activation: WithTanh()},
+		{Size: 1},
+	})
+
+	if len(nn) != 4 || len(nn[0]) != 2 || len(nn[1]) != 3 || len(nn[2]) != 3 || len(nn[3]) != 1 {
+		t.Error("failed to create layers in requested size")
+	}
+
+	for _, tc := range []struct {
+		layer int
+		in    float64
+		out   float64
+	}{
+		{1, -4, Relu(-4)},
+		{2, 1, HyperbolicTangent(1)},
+		{3, 1, Sigmoid(1)}, // default
+	} {
+		for _, n := range nn[tc.layer] {
+			if v := n.Functions.Activation(tc.in); v != tc.out {
+				t.Errorf("layer %v uses wrong activation; expected '%v', got '%v'", tc.layer, tc.out, v)
+			}
+		}
+	}
+
+	in := []float64{.5, 2}
+	out, err := nn.Output(in)
+	if err != nil {
+		t.Error(err)
+	}
+	for idx, n := range nn[0] {
+		n.Preset = &in[idx]
+	}
+	if v := nn[3][0].Value(); v != out[0] {
+		t.Errorf("network failed, expected '%v' got '%v'", v, out[0])
+	}
+}
+
 func Test_Output(t *testing.T) {
 	nn := NewNeuralNet(nil, 1, 3, 1)
 	_, err := nn.Output([]float64{1, 2, 3})
@@ -71,6 +117,359 @@ func Test_Output(t *testing.T) {
 	}
 }
 
+func Test_Inferencer(t *testing.T) {
+	nn := NewNeuralNet(WithTanh(), 2, 4, 4, 2)
+	inputs := [][]float64{
+		{0, 0},
+		{1, -1},
+		{.5, .25},
+		{-3, 2},
+	}
+
+	var expected [][]float64
+	for _, in := range inputs {
+		out, err := nn.Output(in)
+		if err != nil {
+			t.Error(err)
+		}
+		expected = append(expected, out)
+	}
+
+	t.Run("reused", func(t *testing.T) {
+		inf := nn.Inferencer()
+		for idx, in := range inputs {
+			out, err := inf.Output(in)
+			if err != nil {
+				t.Error(err)
+			}
+			for oidx, o := range out {
+				if o != expected[idx][oidx] {
+					t.Errorf("input #%v: wanted %v, got %v", idx, expected[idx], out)
+				}
+			}
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for r := 0; r < 32; r++ {
+			wg.Add(1)
+			go func(r int) {
+				defer wg.Done()
+				idx := r % len(inputs)
+				out, err := nn.Output(inputs[idx])
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				for oidx, o := range out {
+					if o != expected[idx][oidx] {
+						t.Errorf("input #%v: wanted %v, got %v", idx, expected[idx], out)
+					}
+				}
+			}(r)
+		}
+		wg.Wait()
+	})
+
+	t.Run("input not aliased", func(t *testing.T) {
+		in := []float64{1, 2}
+		_, err := nn.Output(in)
+		if err != nil {
+			t.Error(err)
+		}
+		in[0] = 42
+		for _, n := range nn[0] {
+			if n.Value() == 42 {
+				t.Error("network kept reference to input")
+			}
+		}
+	})
+
+	t.Run("wrong dimension", func(t *testing.T) {
+		_, err := nn.Inferencer().Output([]float64{1})
+		if err == nil {
+			t.Error("expected error, didn't get one")
+		}
+
+		err = nn.Inferencer().OutputInto(make([]float64, 1), inputs[0])
+		if err == nil {
+			t.Error("expected error for destination, didn't get one")
+		}
+	})
+
+	t.Run("output into", func(t *testing.T) {
+		inf := nn.Inferencer()
+		dst := make([]float64, 2)
+		for idx, in := range inputs {
+			err := inf.OutputInto(dst, in)
+			if err != nil {
+				t.Error(err)
+			}
+			for oidx, o := range dst {
+				if o != expected[idx][oidx] {
+					t.Errorf("input #%v: wanted %v, got %v", idx, expected[idx], dst)
+				}
+			}
+		}
+	})
+
+	t.Run("zero allocations", func(t *testing.T) {
+		for _, net := range []NeuralNetwork{
+			nn,
+			NewNeuralNetFromSpec([]LayerSpec{{Size: 2}, {Size: 8, Activation: WithRelu()}, {Size: 3, Activation: WithSoftmax()}}),
+		} {
+			inf := net.Inferencer()
+			dst := make([]float64, len(net[len(net)-1]))
+			allocs := testing.AllocsPerRun(100, func() {
+				_ = inf.OutputInto(dst, inputs[1])
+			})
+			if allocs != 0 {
+				t.Errorf("expected no allocations, got %v", allocs)
+			}
+		}
+	})
+
+	t.Run("output without copying weights", func(t *testing.T) {
+		net := NewNeuralNet(WithRelu(), 32, 64, 32, 8)
+		in := make([]float64, 32)
+		allocs := testing.AllocsPerRun(100, func() {
+			_, _ = net.Output(in)
+		})
+
+		// the input and two buffers per layer, independent of the number of weights
+		if expected := float64(2*len(net) - 1); allocs > expected {
+			t.Errorf("expected at most %v allocations, got %v", expected, allocs)
+		}
+	})
+
+	t.Run("compiled", func(t *testing.T) {
+		net := NewNeuralNet(WithTanh(), 2, 4, 2)
+		inf := net.Inferencer()
+		before, err := inf.Output(inputs[1])
+		if err != nil {
+			t.Error(err)
+		}
+
+		net[1][0].Inputs[0].Weight += 1
+		after, err := inf.Output(inputs[1])
+		if err != nil {
+			t.Error(err)
+		}
+		for idx := range before {
+			if before[idx] != after[idx] {
+				t.Error("expected inferencer to keep the weights it was compiled with")
+			}
+		}
+	})
+}
+
+func Test_OutputBatch(t *testing.T) {
+	nn := NewNeuralNet(WithRelu(), 3, 5, 2)
+
+	t.Run("ordered", func(t *testing.T) {
+		var in [][]float64
+		for r := 0; r < 100; r++ {
+			in = append(in, []float64{float64(r), float64(r % 7), -float64(r) / 3})
+		}
+
+		out, err := nn.OutputBatch(in)
+		if err != nil {
+			t.Error(err)
+		}
+
+		if len(out) != len(in) {
+			t.Fatalf("expected %v results, got %v", len(in), len(out))
+		}
+
+		for idx, row := range in {
+			expected, err := nn.Output(row)
+			if err != nil {
+				t.Error(err)
+			}
+			for oidx, o := range expected {
+				if o != out[idx][oidx] {
+					t.Errorf("row #%v: wanted %v, got %v", idx, expected, out[idx])
+				}
+			}
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		out, err := nn.OutputBatch(nil)
+		if err != nil {
+			t.Error(err)
+		}
+		if len(out) != 0 {
+			t.Error("expected no output")
+		}
+	})
+
+	t.Run("wrong dimension", func(t *testing.T) {
+		out, err := nn.OutputBatch([][]float64{{1, 2, 3}, {1, 2}})
+		if err == nil {
+			t.Error("expected error, didn't get one")
+		}
+		if out != nil {
+			t.Error("expected no output")
+		}
+	})
+}
+
+func Test_Parallel(t *testing.T) {
+	for _, tc := range []struct{ workers, n int }{{1, 10}, {3, 10}, {4, 4}, {8, 3}, {2, 0}} {
+		t.Run(fmt.Sprintf("%v_%v", tc.workers, tc.n), func(t *testing.T) {
+			seen := make([]int, tc.n)
+			var mu sync.Mutex
+			parallel(tc.workers, tc.n, func(from int, to int) {
+				mu.Lock()
+				defer mu.Unlock()
+				for idx := from; idx < to; idx++ {
+					seen[idx]++
+				}
+			})
+			for idx, s := range seen {
+				if s != 1 {
+					t.Errorf("index %v handled %v times", idx, s)
+				}
+			}
+		})
+	}
+}
+
+func Test_Forward(t *testing.T) {
+	nn := NewNeuralNet(WithTanh(), 2, 3, 3, 3, 2)
+	in := []float64{.5, -.25}
+	out, err := nn.Output(in)
+	if err != nil {
+		t.Error(err)
+	}
+
+	for idx, n := range nn[0] {
+		n.Preset = &in[idx]
+	}
+
+	for idx, n := range nn[len(nn)-1] {
+		if v := n.Value(); v != out[idx] {
+			t.Errorf("output #%v differs from recursive evaluation; wanted %v, got %v", idx, v, out[idx])
+		}
+	}
+}
+
+func Test_SoftmaxLayer(t *testing.T) {
+	softmax := func(in, hidden, out int) NeuralNetwork {
+		return NewNeuralNetFromSpec([]LayerSpec{
+			{Size: in},
+			{Size: hidden, Activation: WithTanh()},
+			{Size: out, Activation: WithSoftmax()},
+		})
+	}
+
+	t.Run("distribution", func(t *testing.T) {
+		nn := softmax(2, 4, 3)
+		out, err := nn.Output([]float64{.3, -.7})
+		if err != nil {
+			t.Error(err)
+		}
+
+		sum := 0.0
+		for _, o := range out {
+			sum += o
+		}
+		if math.Abs(sum-1) > 1e-12 {
+			t.Errorf("expected distribution, got '%v'", out)
+		}
+	})
+
+	t.Run("combined gradient", func(t *testing.T) {
+		nn := softmax(2, 4, 3)
+		expected := []float64{0, 1, 0}
+		d := nn.Dense()
+		p := d.newPass()
+		d.forward(p, []float64{.3, -.7})
+		d.outputDeltas(p, TrainConfig{Loss: CategoricalCrossEntropy()}, expected)
+		combined := append([]float64{}, p.delta[2]...)
+
+		// without the shortcut, the jacobian of softmax applies to the gradient of the loss
+		d.outputDeltas(p, TrainConfig{Loss: categoricalCrossEntropyJacobian{}}, expected)
+		for idx, d := range combined {
+			if math.Abs(d-p.delta[2][idx]) > 1e-12 {
+				t.Errorf("delta #%v differs; combined %v, jacobian %v", idx, combined, p.delta[2])
+			}
+			if d != p.act[2][idx]-expected[idx] {
+				t.Errorf("delta #%v isn't the difference", idx)
+			}
+		}
+	})
+
+	t.Run("classify", func(t *testing.T) {
+		nn := softmax(2, 6, 3)
+		expectations := []Expectations{
+			{[]float64{1, 0}, []float64{1, 0, 0}},
+			{[]float64{0, 1}, []float64{0, 1, 0}},
+			{[]float64{1, 1}, []float64{0, 0, 1}},
+		}
+		_, err := nn.Train(expectations, .1, RoundStrategy(500), WithLoss(CategoricalCrossEntropy()))
+		if err != nil {
+			t.Error(err)
+		}
+
+		for _, e := range expectations {
+			class, err := nn.Predict(e.Input)
+			if err != nil {
+				t.Error(err)
+			}
+			if class != ArgMax(e.Output) {
+				t.Errorf("wrong class for %v; expected '%v', got '%v'", e.Input, ArgMax(e.Output), class)
+			}
+		}
+	})
+
+	t.Run("hidden layer", func(t *testing.T) {
+		nn := NewNeuralNet(WithTanh(), 2, 3, 1)
+		for _, n := range nn[1] {
+			(*WithSoftmax())(n)
+		}
+		_, err := nn.Train([]Expectations{{[]float64{1, 0}, []float64{.3}}}, .1, RoundStrategy(10), WithLoss(SquaredError()))
+		if err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// categoricalCrossEntropyJacobian is categorical cross entropy without the
+// softmax shortcut
+type categoricalCrossEntropyJacobian struct {
+	categoricalCrossEntropy
+}
+
+func Test_Predict(t *testing.T) {
+	nn := NewNeuralNet(nil, 2, 2)
+	nn[1][0].Inputs[0].Weight, nn[1][0].Inputs[1].Weight, nn[1][0].Bias = 1, 0, 0
+	nn[1][1].Inputs[0].Weight, nn[1][1].Inputs[1].Weight, nn[1][1].Bias = 0, 1, 0
+
+	for _, tc := range []struct {
+		in    []float64
+		class int
+	}{
+		{[]float64{2, 1}, 0},
+		{[]float64{1, 2}, 1},
+	} {
+		class, err := nn.Predict(tc.in)
+		if err != nil {
+			t.Error(err)
+		}
+		if class != tc.class {
+			t.Errorf("failed for %v; expected '%v', got '%v'", tc.in, tc.class, class)
+		}
+	}
+
+	class, err := nn.Predict([]float64{1})
+	if err == nil || class != -1 {
+		t.Error("expected error, didn't get one")
+	}
+}
+
 func Test_Train(t *testing.T) {
 	t.Run("successful", func(t *testing.T) {
 		learningRate := 0.5
@@ -80,6 +479,8 @@ func Test_Train(t *testing.T) {
 		if err != nil {
 			t.Error(err)
 		}
+		in := 5.0
+		nn[0][0].Preset = &in // Output doesn't touch the network; needed to inspect neurons below
 
 		/*
 		 *   w1 / o2 \ w3
@@ -107,7 +508,7 @@ func Test_Train(t *testing.T) {
 		o2out := nn[1][0].Value()
 		o3out := nn[1][1].Value()
 
-		err = nn.Train([]Expectations{{[]float64{5}, []float64{expected}}}, learningRate, RoundStrategy(1))
+		_, err = nn.Train([]Expectations{{[]float64{5}, []float64{expected}}}, learningRate, RoundStrategy(1))
 		if err != nil {
 			t.Error(err)
 		}
@@ -137,9 +538,199 @@ func Test_Train(t *testing.T) {
 		}
 	})
 
+	t.Run("biases", func(t *testing.T) {
+		nn := NewNeuralNet(nil, 1, 2, 1)
+		out, err := nn.Output([]float64{1})
+		if err != nil {
+			t.Error(err)
+		}
+
+		expected := .1
+		learningRate := .5
+		b := nn[2][0].Bias
+		delta := (out[0] - expected) * DerivativeSigmoid(nn[2][0].Input())
+		bchg := learningRate * (DerivativeSigmoid(nn[2][0].Input()) * 1.0 * delta)
+
+		_, err = nn.Train([]Expectations{{[]float64{1}, []float64{expected}}}, learningRate, RoundStrategy(1))
+		if err != nil {
+			t.Error(err)
+		}
+
+		if b-bchg != nn[2][0].Bias {
+			t.Errorf("bias was calculated wrongly; wanted %v, got %v", b-bchg, nn[2][0].Bias)
+		}
+	})
+
+	t.Run("frozen biases", func(t *testing.T) {
+		nn := NewNeuralNet(nil, 1, 2, 1)
+		var biases []float64
+		for _, l := range nn {
+			for _, n := range l {
+				biases = append(biases, n.Bias)
+			}
+		}
+
+		_, err := nn.Train([]Expectations{{[]float64{1}, []float64{.1}}}, .5, RoundStrategy(10), WithFrozenBiases())
+		if err != nil {
+			t.Error(err)
+		}
+
+		idx := 0
+		for _, l := range nn {
+			for _, n := range l {
+				if n.Bias != biases[idx] {
+					t.Errorf("bias #%v changed; wanted %v, got %v", idx, biases[idx], n.Bias)
+				}
+				idx++
+			}
+		}
+	})
+
+	t.Run("frozen biases with optimizer state", func(t *testing.T) {
+		nn := NewNeuralNet(nil, 1, 2, 1)
+		expectations := []Expectations{{[]float64{1}, []float64{.1}}}
+		_, err := nn.Train(expectations, .5, RoundStrategy(5), WithOptimizer(Momentum(.9)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the velocity of the first run must not move the biases on resume
+		bias := nn[2][0].Bias
+		velocity := nn[2][0].BiasOptimizer.Velocity
+		_, err = nn.Train(expectations, .5, RoundStrategy(5), WithOptimizer(Momentum(.9)), WithFrozenBiases())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nn[2][0].Bias != bias {
+			t.Errorf("bias changed; wanted %v, got %v", bias, nn[2][0].Bias)
+		}
+		if nn[2][0].BiasOptimizer.Velocity != velocity {
+			t.Errorf("bias state changed; wanted %v, got %v", velocity, nn[2][0].BiasOptimizer.Velocity)
+		}
+	})
+
+	t.Run("batch size", func(t *testing.T) {
+		expectations := []Expectations{
+			{[]float64{1}, []float64{.2}},
+			{[]float64{2}, []float64{.4}},
+			{[]float64{3}, []float64{.6}},
+		}
+
+		online := NewNeuralNet(nil, 1, 2, 1)
+		content, err := online.Serialize()
+		if err != nil {
+			t.Error(err)
+		}
+		batched, err := NewNeuralNetFromSerialized(nil, content)
+		if err != nil {
+			t.Error(err)
+		}
+		full, err := NewNeuralNetFromSerialized(nil, content)
+		if err != nil {
+			t.Error(err)
+		}
+
+		// expected full-batch update is the mean of all changes on unchanged weights
+		expected, err := NewNeuralNetFromSerialized(nil, content)
+		if err != nil {
+			t.Error(err)
+		}
+		dense := expected.Dense()
+		p := dense.newPass()
+		for _, e := range expectations {
+			dense.forward(p, e.Input)
+			out := p.act[2][0]
+			d := DerivativeSigmoid(p.pre[2][0])
+			p.delta[2][0] = d * 1.0 * ((out - e.Output[0]) * d)
+			dense.backward(p, .5, true)
+		}
+		dense.average(len(expectations))
+		dense.apply(TrainConfig{}, .5)
+		dense.CopyTo(expected)
+
+		_, err = online.Train(expectations, .5, RoundStrategy(1))
+		if err != nil {
+			t.Error(err)
+		}
+		_, err = batched.Train(expectations, .5, RoundStrategy(1), WithBatchSize(1))
+		if err != nil {
+			t.Error(err)
+		}
+		_, err = full.Train(expectations, .5, RoundStrategy(1), WithBatchSize(len(expectations)))
+		if err != nil {
+			t.Error(err)
+		}
+
+		for lidx, l := range online {
+			for nidx, n := range l {
+				for iidx, i := range n.Inputs {
+					if i.Weight != batched[lidx][nidx].Inputs[iidx].Weight {
+						t.Errorf("batch size 1 differs from online learning at %v/%v/%v", lidx, nidx, iidx)
+					}
+					if w := expected[lidx][nidx].Inputs[iidx].Weight; w != full[lidx][nidx].Inputs[iidx].Weight {
+						t.Errorf("full batch wrong at %v/%v/%v; wanted %v, got %v", lidx, nidx, iidx, w, full[lidx][nidx].Inputs[iidx].Weight)
+					}
+				}
+			}
+		}
+	})
+
+	t.Run("workers", func(t *testing.T) {
+		expectations := []Expectations{
+			{[]float64{1, 2}, []float64{.2, .8}},
+			{[]float64{2, 1}, []float64{.8, .2}},
+		}
+		train := func(workers int) string {
+			nn := NewNeuralNetFromSpec(
+				[]LayerSpec{{Size: 2}, {Size: 9, Activation: WithTanh()}, {Size: 7, Activation: WithTanh()}, {Size: 2}},
+				WithRand(rand.New(rand.NewPCG(3, 3))),
+			)
+			_, err := nn.Train(expectations, .1, RoundStrategy(20), WithWorkers(workers))
+			if err != nil {
+				t.Error(err)
+			}
+			content, err := nn.Serialize()
+			if err != nil {
+				t.Error(err)
+			}
+			return content
+		}
+
+		sequential := train(1)
+		for _, workers := range []int{2, 3, 4, 16} {
+			if train(workers) != sequential {
+				t.Errorf("%v workers differ from sequential training", workers)
+			}
+		}
+	})
+
+	t.Run("invalid workers", func(t *testing.T) {
+		nn := NewNeuralNet(nil, 1, 2, 1)
+		_, err := nn.Train([]Expectations{{[]float64{1}, []float64{1}}}, .5, RoundStrategy(1), WithWorkers(0))
+		if err == nil {
+			t.Error("expected error got none")
+		}
+	})
+
+	t.Run("invalid batch size", func(t *testing.T) {
+		nn := NewNeuralNet(nil, 1, 2, 1)
+		_, err := nn.Train([]Expectations{{[]float64{1}, []float64{1}}}, .5, RoundStrategy(1), WithBatchSize(0))
+		if err == nil {
+			t.Error("expected error got none")
+		}
+	})
+
+	t.Run("deep network", func(t *testing.T) {
+		nn := NewNeuralNet(WithTanh(), 4, 8, 8, 8, 8, 8, 8, 1)
+		_, err := nn.Train([]Expectations{{[]float64{.1, .2, .3, .4}, []float64{.5}}}, .01, RoundStrategy(200))
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
 	t.Run("error-nous run input", func(t *testing.T) {
 		nn := NewNeuralNet(nil, 1, 2, 1)
-		err := nn.Train([]Expectations{{[]float64{1, 2}, []float64{1}}}, .5, RoundStrategy(1))
+		_, err := nn.Train([]Expectations{{[]float64{1, 2}, []float64{1}}}, .5, RoundStrategy(1))
 		if err == nil {
 			t.Error("expected error got none")
 		}
@@ -147,7 +738,7 @@ func Test_Train(t *testing.T) {
 
 	t.Run("error-nous run output", func(t *testing.T) {
 		nn := NewNeuralNet(nil, 1, 2, 1)
-		err := nn.Train([]Expectations{{[]float64{1}, []float64{1, 2}}}, .5, RoundStrategy(1))
+		_, err := nn.Train([]Expectations{{[]float64{1}, []float64{1, 2}}}, .5, RoundStrategy(1))
 		if err == nil {
 			t.Error("expected error got none")
 		}
@@ -155,13 +746,49 @@ func Test_Train(t *testing.T) {
 
 	t.Run("setting negative rounds", func(t *testing.T) {
 		nn := NewNeuralNet(nil, 1, 2, 1)
-		err := nn.Train([]Expectations{{[]float64{1}, []float64{1}}}, .5, RoundStrategy(-5))
+		_, err := nn.Train([]Expectations{{[]float64{1}, []float64{1}}}, .5, RoundStrategy(-5))
 		if err != nil {
 			t.Error(err)
 		}
 	})
 }
 
+func Test_Deterministic(t *testing.T) {
+	expectations := []Expectations{
+		{[]float64{0, 0}, []float64{0}},
+		{[]float64{0, 1}, []float64{1}},
+		{[]float64{1, 0}, []float64{1}},
+		{[]float64{1, 1}, []float64{0}},
+	}
+
+	train := func(seed uint64) string {
+		nn := NewNeuralNetFromSpec(
+			[]LayerSpec{{Size: 2}, {Size: 16, Activation: WithTanh()}, {Size: 16, Activation: WithTanh()}, {Size: 1}},
+			WithRand(rand.New(rand.NewPCG(7, 7))),
+		)
+		_, err := nn.Train(expectations, .3, RoundStrategy(50), WithDeterministic(seed), WithBatchSize(3))
+		if err != nil {
+			t.Error(err)
+		}
+		content, err := nn.Serialize()
+		if err != nil {
+			t.Error(err)
+		}
+		return content
+	}
+
+	first := train(1)
+	for range 5 {
+		if train(1) != first {
+			t.Fatal("expected bit-identical networks")
+		}
+	}
+
+	if train(2) == first {
+		t.Error("expected a different shuffle for a different seed")
+	}
+}
+
 func Test_UpdateWeight(t *testing.T) {
 	i := &Input{
 		Weight:        .55,
@@ -174,6 +801,18 @@ func Test_UpdateWeight(t *testing.T) {
 	}
 }
 
+func Test_UpdateBias(t *testing.T) {
+	n := &Neuron{
+		Bias:              .55,
+		PendingBiasChange: -.001,
+	}
+	n.UpdateBias()
+
+	if n.Bias != (.55-(-.001)) || n.PendingBiasChange != 0.0 {
+		t.Error("updating bias failed")
+	}
+}
+
 func Test_Serialize(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		nn := NewNeuralNet(WithRelu(), 2, 2, 1)
@@ -227,6 +866,131 @@ func Test_Serialize(t *testing.T) {
 		}
 	})
 
+	t.Run("stored activations", func(t *testing.T) {
+		nn := NewNeuralNetFromSpec([]LayerSpec{
+			{Size: 2},
+			{Size: 3, Activation: WithRelu()},
+			{Size: 3, Activation: WithTanh()},
+			{Size: 2, Activation: WithSoftmax()},
+		})
+		out1, err := nn.Output([]float64{2, 3})
+		if err != nil {
+			t.Error(err)
+		}
+		content, err := nn.Serialize()
+		if err != nil {
+			t.Error(err)
+		}
+		nn, err = NewNeuralNetFromSerialized(nil, content)
+		if err != nil {
+			t.Error(err)
+		}
+		out2, err := nn.Output([]float64{2, 3})
+		if err != nil {
+			t.Error(err)
+		}
+
+		for idx, o := range out1 {
+			if o != out2[idx] {
+				t.Errorf("failed to compare output #%v: wanted %v, got %v", idx, o, out2[idx])
+			}
+		}
+	})
+
+	t.Run("contradicting activation", func(t *testing.T) {
+		nn := NewNeuralNet(WithTanh(), 2, 2, 1)
+		content, err := nn.Serialize()
+		if err != nil {
+			t.Error(err)
+		}
+		n, err := NewNeuralNetFromSerialized(WithRelu(), content)
+		if n != nil {
+			t.Error("expected no output")
+		}
+
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("without stored activation", func(t *testing.T) {
+		s := base64.StdEncoding.EncodeToString([]byte(`[[{"inputs":null,"bias":0,"preset":1}],[{"inputs":[{"weight":0.5}],"bias":0.25,"preset":null}]]`))
+		nn, err := NewNeuralNetFromSerialized(WithTanh(), s)
+		if err != nil {
+			t.Error(err)
+		}
+		out, err := nn.Output([]float64{1})
+		if err != nil {
+			t.Error(err)
+		}
+		if out[0] != HyperbolicTangent(.75) {
+			t.Errorf("expected requested activation; wanted %v, got %v", HyperbolicTangent(.75), out[0])
+		}
+
+		nn, err = NewNeuralNetFromSerialized(nil, s)
+		if err != nil {
+			t.Error(err)
+		}
+		out, err = nn.Output([]float64{1})
+		if err != nil {
+			t.Error(err)
+		}
+		if out[0] != Sigmoid(.75) {
+			t.Errorf("expected default activation; wanted %v, got %v", Sigmoid(.75), out[0])
+		}
+	})
+
+	t.Run("fallback activation", func(t *testing.T) {
+		s := base64.StdEncoding.EncodeToString([]byte(`[[{"inputs":null,"bias":0,"preset":1}],[{"inputs":[{"weight":0.5}],"bias":0.25,"preset":null}]]`))
+		nn, err := NewNeuralNetFromSerialized(WithTanh(), s, WithFallbackActivation())
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := nn.Output([]float64{1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out[0] != HyperbolicTangent(.75) {
+			t.Errorf("expected fallback activation; wanted %v, got %v", HyperbolicTangent(.75), out[0])
+		}
+
+		content, err := NewNeuralNet(WithRelu(), 2, 2, 1).Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		nn, err = NewNeuralNetFromSerialized(WithTanh(), content, WithFallbackActivation())
+		if err != nil {
+			t.Fatalf("expected stored activation to be kept, got '%v'", err)
+		}
+		if name := nn[1][0].Functions.Name; name != "relu" {
+			t.Errorf("expected stored activation 'relu', got '%v'", name)
+		}
+	})
+
+	t.Run("unknown stored activation", func(t *testing.T) {
+		s := base64.StdEncoding.EncodeToString([]byte(`[[{"inputs":null,"bias":0,"preset":1,"activation":"nope"}]]`))
+		n, err := NewNeuralNetFromSerialized(nil, s)
+		if n != nil {
+			t.Error("expected no output")
+		}
+
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("unnamed activation", func(t *testing.T) {
+		nn := NewNeuralNet(withFunctions(NeuronFunctions{Activation: Relu, Derivative: DerivativeRelu}), 2, 2, 1)
+		content, err := nn.Serialize()
+		if content != "" {
+			t.Error("expected no output")
+		}
+
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+
 	t.Run("json error serialize", func(t *testing.T) {
 		nn := NewNeuralNet(nil, 1, 2, 1)
 		nn[1][0].Bias = math.Inf(1)
@@ -292,7 +1056,7 @@ func Test_RoundStrategy(t *testing.T) {
 	n := 0
 	s := RoundStrategy(5)
 	for {
-		if !s([]float64{}) {
+		if !s(TrainingState{}) {
 			break
 		}
 		n++
@@ -307,6 +1071,84 @@ func Test_RoundStrategy(t *testing.T) {
 	}
 }
 
+func Test_EarlyStopping(t *testing.T) {
+	t.Run("patience", func(t *testing.T) {
+		s := EarlyStopping(2, .01)
+		losses := []float64{1, .5, .495, .3, .3, .299}
+		if !s(TrainingState{}) {
+			t.Fatal("stopped before training")
+		}
+
+		n := 0
+		for idx, l := range losses {
+			if !s(TrainingState{Epoch: idx + 1, Validated: true, ValidationLoss: l, Loss: 42}) {
+				break
+			}
+			n++
+		}
+
+		// .495 improves by less than .01, .3 resets patience, then .3 and .299 exhaust it
+		if n != 5 {
+			t.Errorf("expected to stop after 5 epochs, got %v", n)
+		}
+	})
+
+	t.Run("training loss without validation", func(t *testing.T) {
+		s := EarlyStopping(1, 0)
+		s(TrainingState{})
+		if !s(TrainingState{Epoch: 1, Loss: 1}) {
+			t.Error("stopped on improvement")
+		}
+		if s(TrainingState{Epoch: 2, Loss: 1}) {
+			t.Error("didn't stop without improvement")
+		}
+	})
+}
+
+func Test_RestoreBest(t *testing.T) {
+	// the validation set contradicts the training set, so every epoch makes the
+	// validation loss worse
+	expectations := []Expectations{{Input: []float64{1}, Output: []float64{1}}}
+	validation := []Expectations{{Input: []float64{1}, Output: []float64{0}}}
+
+	t.Run("restored", func(t *testing.T) {
+		nn := NewNeuralNet(nil, 1, 2, 1)
+		result, err := nn.Train(expectations, .5, EarlyStopping(3, 0), WithValidation(validation), WithRestoreBest())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Reason != StopEarly || len(result.Epochs) != 4 {
+			t.Errorf("expected early stop after 4 epochs, got %+v", result)
+		}
+
+		best := result.Epochs[0]
+		for _, e := range result.Epochs {
+			if e.ValidationLoss < best.ValidationLoss {
+				best = e
+			}
+		}
+		if result.BestEpoch != best.Epoch {
+			t.Errorf("expected best epoch %v, got %v", best.Epoch, result.BestEpoch)
+		}
+
+		out, err := nn.Output(validation[0].Input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l := SquaredError().Value(out[0], 0); l != best.ValidationLoss {
+			t.Errorf("expected weights of best epoch with loss %v, got %v", best.ValidationLoss, l)
+		}
+	})
+
+	t.Run("without validation", func(t *testing.T) {
+		nn := NewNeuralNet(nil, 1, 2, 1)
+		_, err := nn.Train(expectations, .5, RoundStrategy(1), WithRestoreBest())
+		if err == nil {
+			t.Error("expected error, didn't get one")
+		}
+	})
+}
+
 func Test_ThresholdStrategy(t *testing.T) {
 	t.Run("without max time", func(t *testing.T) {
 		s := ThresholdStrategy(0.01, -1)
@@ -317,7 +1159,7 @@ func Test_ThresholdStrategy(t *testing.T) {
 		}
 
 		for {
-			if !s(errs) {
+			if !s(TrainingState{Errors: errs}) {
 				break
 			}
 
@@ -340,6 +1182,13 @@ func Test_ThresholdStrategy(t *testing.T) {
 		}
 	})
 
+	t.Run("with non-finite errors", func(t *testing.T) {
+		s := ThresholdStrategy(0.01, -1)
+		if s(TrainingState{Errors: []float64{math.NaN()}}) {
+			t.Error("failed to stop on NaN")
+		}
+	})
+
 	t.Run("with max time", func(t *testing.T) {
 		now := time.Now()
 		s := ThresholdStrategy(0.01, time.Millisecond)
@@ -350,7 +1199,7 @@ func Test_ThresholdStrategy(t *testing.T) {
 		}
 
 		for {
-			if !s(errs) {
+			if !s(TrainingState{Errors: errs}) {
 				break
 			}
 
@@ -386,7 +1235,7 @@ func Test_WithLoggingStrategy(t *testing.T) {
 	}
 
 	for {
-		if !s(errs) {
+		if !s(TrainingState{Errors: errs}) {
 			break
 		}
 
@@ -413,3 +1262,95 @@ func Test_WithLoggingStrategy(t *testing.T) {
 		t.Error("failed to write log")
 	}
 }
+
+func Test_TrainContext(t *testing.T) {
+	expectations := make([]Expectations, 8)
+	for idx := range expectations {
+		v := float64(idx) / 8
+		expectations[idx] = Expectations{Input: []float64{v, 1 - v}, Output: []float64{v}}
+	}
+
+	t.Run("cancelled upfront", func(t *testing.T) {
+		nn := NewNeuralNet(nil, 2, 3, 1)
+		before, err := nn.Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		result, err := nn.TrainContext(ctx, expectations, .5, RoundStrategy(10))
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected cancellation, got '%v'", err)
+		}
+		if result.Reason != StopCancelled || len(result.Epochs) != 0 {
+			t.Errorf("unexpected result %+v", result)
+		}
+
+		after, err := nn.Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if before != after {
+			t.Error("expected network to be unchanged")
+		}
+	})
+
+	t.Run("incomplete batch discarded", func(t *testing.T) {
+		nn := NewNeuralNet(nil, 2, 3, 1)
+		content, err := nn.Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// a metric sees every expectation, so it cancels within the second batch
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		seen := 0
+		counter := func(_ []float64, _ []float64) float64 {
+			seen++
+			if seen == 6 {
+				cancel()
+			}
+			return 0
+		}
+		_, err = nn.TrainContext(ctx, expectations, .5, RoundStrategy(10), WithBatchSize(4), WithMetric("counter", counter))
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected cancellation, got '%v'", err)
+		}
+
+		expected, err := NewNeuralNetFromSerialized(nil, content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = expected.Train(expectations[:4], .5, RoundStrategy(1), WithBatchSize(4))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := nn.Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := expected.Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Error("expected only the first batch to be applied")
+		}
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		nn := NewNeuralNet(nil, 2, 3, 1)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		result, err := nn.TrainContext(ctx, expectations, .5, RoundStrategy(math.MaxInt))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected deadline, got '%v'", err)
+		}
+		if result.Reason != StopCancelled {
+			t.Errorf("expected reason '%v', got '%v'", StopCancelled, result.Reason)
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package qndnn
+
+import "math"
+
+// OptimizerState is the per weight (or bias) state of an optimizer; it is
+// serialized with the network, so training can be resumed with the same
+// optimizer
+type OptimizerState struct {
+	Velocity float64 `json:"velocity"` // momentum or first moment
+	Cache    float64 `json:"cache"`    // (decaying) sum of squared gradients or second moment
+	Steps    int     `json:"steps"`
+}
+
+// Optimizer turns the pending change of a parameter – the plain gradient
+// descent step (learning rate * gradient) – into the change actually applied
+type Optimizer interface {
+	Step(s *OptimizerState, change float64, learningRate float64) float64
+}
+
+type sgd struct{}
+
+func (sgd) Step(_ *OptimizerState, change float64, _ float64) float64 {
+	return change
+}
+
+// SGD applies pending changes as they are; same as Update
+func SGD() Optimizer {
+	return sgd{}
+}
+
+type momentum struct {
+	mu       float64
+	nesterov bool
+}
+
+func (o momentum) Step(s *OptimizerState, change float64, _ float64) float64 {
+	s.Velocity = o.mu*s.Velocity + change
+	s.Steps++
+	if o.nesterov {
+		return o.mu*s.Velocity + change // look ahead along the updated velocity
+	}
+	return s.Velocity
+}
+
+// Momentum accumulates a velocity from past changes, decayed by mu (commonly 0.9)
+func Momentum(mu float64) Optimizer {
+	return momentum{mu: mu}
+}
+
+// Nesterov is Momentum evaluating the change at the looked-ahead position
+func Nesterov(mu float64) Optimizer {
+	return momentum{mu: mu, nesterov: true}
+}
+
+// gradient recovers the gradient from the pending change for optimizers
+// scaling the learning rate per parameter
+func gradient(change float64, learningRate float64) (float64, bool) {
+	if learningRate == 0 {
+		return 0, false
+	}
+	return change / learningRate, true
+}
+
+type adaGrad struct {
+	epsilon float64
+}
+
+func (o adaGrad) Step(s *OptimizerState, change float64, learningRate float64) float64 {
+	g, ok := gradient(change, learningRate)
+	if !ok {
+		return 0
+	}
+
+	s.Cache += g * g
+	s.Steps++
+	return learningRate * g / (math.Sqrt(s.Cache) + o.epsilon)
+}
+
+// AdaGrad scales the learning rate per parameter by all past squared gradients;
+// epsilon (commonly 1e-8) avoids division by zero
+func AdaGrad(epsilon float64) Optimizer {
+	return adaGrad{epsilon: epsilon}
+}
+
+type rmsProp struct {
+	decay   float64
+	epsilon float64
+}
+
+func (o rmsProp) Step(s *OptimizerState, change float64, learningRate float64) float64 {
+	g, ok := gradient(change, learningRate)
+	if !ok {
+		return 0
+	}
+
+	s.Cache = o.decay*s.Cache + (1-o.decay)*g*g
+	s.Steps++
+	return learningRate * g / (math.Sqrt(s.Cache) + o.epsilon)
+}
+
+// RMSProp scales the learning rate per parameter by a decaying average of
+// squared gradients (decay commonly 0.9, epsilon 1e-8)
+func RMSProp(decay float64, epsilon float64) Optimizer {
+	return rmsProp{decay: decay, epsilon: epsilon}
+}
+
+type adam struct {
+	beta1   float64
+	beta2   float64
+	epsilon float64
+}
+
+func (o adam) Step(s *OptimizerState, change float64, learningRate float64) float64 {
+	g, ok := gradient(change, learningRate)
+	if !ok {
+		return 0
+	}
+
+	s.Steps++
+	s.Velocity = o.beta1*s.Velocity + (1-o.beta1)*g
+	s.Cache = o.beta2*s.Cache + (1-o.beta2)*g*g
+
+	// bias corrected moments
+	m := s.Velocity / (1 - math.Pow(o.beta1, float64(s.Steps)))
+	v := s.Cache / (1 - math.Pow(o.beta2, float64(s.Steps)))
+	return learningRate * m / (math.Sqrt(v) + o.epsilon)
+}
+
+// Adam combines momentum and RMSProp with bias corrected moments (commonly
+// beta1 0.9, beta2 0.999, epsilon 1e-8)
+func Adam(beta1 float64, beta2 float64, epsilon float64) Optimizer {
+	return adam{beta1: beta1, beta2: beta2, epsilon: epsilon}
+}
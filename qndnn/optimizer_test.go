@@ -0,0 +1,146 @@
+package qndnn
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func Test_Optimizer(t *testing.T) {
+	changes := []float64{.1, -.05, .2}
+	learningRate := .5
+
+	for _, tc := range []struct {
+		name string
+		o    Optimizer
+		out  []float64
+	}{
+		{"sgd", SGD(), []float64{.1, -.05, .2}},
+		{"momentum", Momentum(.9), []float64{.1, .04, .236}},
+		{"nesterov", Nesterov(.9), []float64{.19, -.014, .4124}},
+		{"adagrad", AdaGrad(0), []float64{
+			.5,
+			.5 * -.1 / math.Sqrt(.04+.01),
+			.5 * .4 / math.Sqrt(.04+.01+.16),
+		}},
+		{"rmsprop", RMSProp(.9, 0), []float64{
+			.5 * .2 / math.Sqrt(.1*.04),
+			.5 * -.1 / math.Sqrt(.9*.1*.04+.1*.01),
+			.5 * .4 / math.Sqrt(.9*(.9*.1*.04+.1*.01)+.1*.16),
+		}},
+		{"adam", Adam(.9, .999, 0), []float64{.5, 0.13316851983020048, 0.329055800127217}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &OptimizerState{}
+			for idx, c := range changes {
+				out := tc.o.Step(s, c, learningRate)
+				if math.Abs(out-tc.out[idx]) > 1e-12 {
+					t.Errorf("step #%v: expected '%v', got '%v'", idx, tc.out[idx], out)
+				}
+			}
+		})
+	}
+
+	t.Run("zero learning rate", func(t *testing.T) {
+		for _, o := range []Optimizer{AdaGrad(1e-8), RMSProp(.9, 1e-8), Adam(.9, .999, 1e-8)} {
+			if out := o.Step(&OptimizerState{}, .1, 0); out != 0 {
+				t.Errorf("expected no change, got '%v'", out)
+			}
+		}
+	})
+}
+
+func Test_TrainWithOptimizer(t *testing.T) {
+	expectations := []Expectations{
+		{[]float64{0, 1}, []float64{1}},
+		{[]float64{1, 0}, []float64{1}},
+		{[]float64{1, 1}, []float64{0}},
+	}
+
+	for _, o := range []Optimizer{
+		SGD(),
+		Momentum(.9),
+		Nesterov(.9),
+		AdaGrad(1e-8),
+		RMSProp(.9, 1e-8),
+		Adam(.9, .999, 1e-8),
+	} {
+		t.Run(fmt.Sprintf("%T resumed", o), func(t *testing.T) {
+			nn := NewNeuralNet(WithTanh(), 2, 3, 1)
+			content, err := nn.Serialize()
+			if err != nil {
+				t.Error(err)
+			}
+
+			_, err = nn.Train(expectations, .05, RoundStrategy(20), WithOptimizer(o))
+			if err != nil {
+				t.Error(err)
+			}
+
+			// train the same net in two runs, serializing in between
+			resumed, err := NewNeuralNetFromSerialized(WithTanh(), content)
+			if err != nil {
+				t.Error(err)
+			}
+			_, err = resumed.Train(expectations, .05, RoundStrategy(10), WithOptimizer(o))
+			if err != nil {
+				t.Error(err)
+			}
+			content, err = resumed.Serialize()
+			if err != nil {
+				t.Error(err)
+			}
+			resumed, err = NewNeuralNetFromSerialized(WithTanh(), content)
+			if err != nil {
+				t.Error(err)
+			}
+			_, err = resumed.Train(expectations, .05, RoundStrategy(10), WithOptimizer(o))
+			if err != nil {
+				t.Error(err)
+			}
+
+			for lidx, l := range nn {
+				for nidx, n := range l {
+					if n.Bias != resumed[lidx][nidx].Bias {
+						t.Errorf("bias %v/%v differs after resuming", lidx, nidx)
+					}
+					for iidx, i := range n.Inputs {
+						if i.Weight != resumed[lidx][nidx].Inputs[iidx].Weight {
+							t.Errorf("weight %v/%v/%v differs after resuming", lidx, nidx, iidx)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func Test_TrainWithStatelessOptimizer(t *testing.T) {
+	nn := NewNeuralNet(WithTanh(), 2, 3, 1)
+	_, err := nn.Train([]Expectations{{[]float64{0, 1}, []float64{1}}}, .05, RoundStrategy(2), WithOptimizer(SGD()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for lidx, l := range nn[1:] {
+		for nidx, n := range l {
+			if n.BiasOptimizer != nil || n.Inputs[0].Optimizer != nil {
+				t.Errorf("expected no optimizer state for neuron %v/%v", lidx+1, nidx)
+			}
+		}
+	}
+
+	content, err := nn.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte("optimizer")) {
+		t.Errorf("expected optimizer state to be omitted, got %s", raw)
+	}
+}
@@ -0,0 +1,125 @@
+package qndnn
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// StopReason tells why training ended
+type StopReason string
+
+const (
+	StopStrategy  StopReason = "strategy" // a strategy returned false without naming a reason
+	StopRounds    StopReason = "rounds exhausted"
+	StopThreshold StopReason = "threshold reached"
+	StopTimeout   StopReason = "timeout"
+	StopEarly     StopReason = "early stopping"
+	StopPlateau   StopReason = "plateau"
+	StopCancelled StopReason = "cancelled"
+	StopDiverged  StopReason = "diverged"
+)
+
+// Metric scores the output of a single expectation, e.g. Accuracy; Train
+// reports its mean per epoch
+type Metric func(out []float64, expected []float64) float64
+
+// WithMetric reports the mean of the metric over every epoch (and validation
+// set) under name
+func WithMetric(name string, m Metric) TrainOption {
+	return func(c *TrainConfig) {
+		if c.Metrics == nil {
+			c.Metrics = map[string]Metric{}
+		}
+		c.Metrics[name] = m
+	}
+}
+
+// Accuracy scores 1 if the highest output matches the highest expected output,
+// i.e. the class is predicted correctly, and 0 otherwise
+func Accuracy() Metric {
+	return func(out []float64, expected []float64) float64 {
+		if ArgMax(out) == ArgMax(expected) {
+			return 1
+		}
+		return 0
+	}
+}
+
+// TrainResult is the history of a training run
+type TrainResult struct {
+	Epochs    []TrainingState `json:"epochs"` // state after every completed epoch
+	Elapsed   time.Duration   `json:"elapsed"`
+	Reason    StopReason      `json:"reason"`
+	BestEpoch int             `json:"best_epoch,omitempty"` // epoch with the lowest validation loss; see WithRestoreBest
+}
+
+func (r *TrainResult) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+// WriteCSV writes a row per epoch; metrics are written in order of their
+// names, validation columns are empty for epochs without validation
+func (r *TrainResult) WriteCSV(w io.Writer) error {
+	metrics := map[string]bool{}
+	for _, e := range r.Epochs {
+		for name := range e.Metrics {
+			metrics[name] = true
+		}
+		for name := range e.ValidationMetrics {
+			metrics[name] = true
+		}
+	}
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	header := []string{"epoch", "step", "loss", "total_loss", "validation_loss", "elapsed_ms"}
+	for _, name := range names {
+		header = append(header, name, "validation_"+name)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	format := func(v float64) string {
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	for _, e := range r.Epochs {
+		validation := ""
+		if e.Validated {
+			validation = format(e.ValidationLoss)
+		}
+		row := []string{
+			strconv.Itoa(e.Epoch),
+			strconv.Itoa(e.Step),
+			format(e.Loss),
+			format(e.TotalLoss),
+			validation,
+			format(float64(e.Elapsed) / float64(time.Millisecond)),
+		}
+		for _, name := range names {
+			metric, validationMetric := "", ""
+			if v, ok := e.Metrics[name]; ok {
+				metric = format(v)
+			}
+			if v, ok := e.ValidationMetrics[name]; ok {
+				validationMetric = format(v)
+			}
+			row = append(row, metric, validationMetric)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
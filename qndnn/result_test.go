@@ -0,0 +1,166 @@
+package qndnn
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_TrainResult(t *testing.T) {
+	expectations := []Expectations{
+		{Input: []float64{0, 1}, Output: []float64{1, 0}},
+		{Input: []float64{1, 0}, Output: []float64{0, 1}},
+	}
+
+	t.Run("history", func(t *testing.T) {
+		nn := NewNeuralNet(nil, 2, 3, 2)
+		result, err := nn.Train(expectations, .5, RoundStrategy(4))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(result.Epochs) != 4 {
+			t.Fatalf("expected 4 epochs, got %v", len(result.Epochs))
+		}
+		for idx, e := range result.Epochs {
+			if e.Epoch != idx+1 || e.Loss == 0 {
+				t.Errorf("unexpected epoch #%v: %+v", idx, e)
+			}
+		}
+		if result.Elapsed < result.Epochs[3].Elapsed {
+			t.Error("expected elapsed time to cover all epochs")
+		}
+	})
+
+	t.Run("reasons", func(t *testing.T) {
+		tcs := []struct {
+			name     string
+			strategy Strategy
+			expected StopReason
+		}{
+			{"rounds", RoundStrategy(2), StopRounds},
+			{"threshold", ThresholdStrategy(1000, -1), StopThreshold},
+			{"timeout", ThresholdStrategy(0, time.Nanosecond), StopTimeout},
+			{"strategy", func(s TrainingState) bool { return s.Epoch < 1 }, StopStrategy},
+			{"named", func(s TrainingState) bool { return s.Stop(StopEarly) }, StopEarly},
+			{"logged", WithLoggingStrategy(&bytes.Buffer{}, RoundStrategy(1)), StopRounds},
+		}
+
+		for _, tc := range tcs {
+			t.Run(tc.name, func(t *testing.T) {
+				nn := NewNeuralNet(nil, 2, 3, 2)
+				result, err := nn.Train(expectations, .5, tc.strategy)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.Reason != tc.expected {
+					t.Errorf("expected reason '%v', got '%v'", tc.expected, result.Reason)
+				}
+			})
+		}
+	})
+
+	t.Run("diverged", func(t *testing.T) {
+		nn := NewNeuralNet(WithLinear(), 1, 2, 1)
+		result, err := nn.Train([]Expectations{{[]float64{10}, []float64{1000}}}, 10, RoundStrategy(1000))
+		if !errors.Is(err, ErrDiverged) {
+			t.Fatalf("expected divergence, got '%v'", err)
+		}
+		if result.Reason != StopDiverged {
+			t.Errorf("expected reason '%v', got '%v'", StopDiverged, result.Reason)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		nn := NewNeuralNet(nil, 2, 3, 2)
+		result, err := nn.Train(expectations, .5, RoundStrategy(1), WithBatchSize(0))
+		if err == nil || result != nil {
+			t.Errorf("expected error without result, got '%v', '%v'", result, err)
+		}
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		nn := NewNeuralNet(nil, 2, 3, 2)
+		result, err := nn.Train(
+			expectations,
+			.5,
+			RoundStrategy(2),
+			WithMetric("accuracy", Accuracy()),
+			WithValidation(expectations[:1]),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, e := range result.Epochs {
+			a, ok := e.Metrics["accuracy"]
+			if !ok || (a != 0 && a != .5 && a != 1) {
+				t.Errorf("unexpected accuracy in %+v", e.Metrics)
+			}
+			v, ok := e.ValidationMetrics["accuracy"]
+			if !ok || (v != 0 && v != 1) {
+				t.Errorf("unexpected validation accuracy in %+v", e.ValidationMetrics)
+			}
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		result := &TrainResult{
+			Epochs: []TrainingState{
+				{Epoch: 1, Step: 2, Loss: .5, TotalLoss: 1, Elapsed: 2 * time.Millisecond, Metrics: map[string]float64{"accuracy": .5}},
+				{Epoch: 2, Step: 4, Loss: .25, TotalLoss: .5, Elapsed: 3 * time.Millisecond, Validated: true, ValidationLoss: .75},
+			},
+		}
+
+		buf := &bytes.Buffer{}
+		err := result.WriteCSV(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rows, err := csv.NewReader(buf).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := [][]string{
+			{"epoch", "step", "loss", "total_loss", "validation_loss", "elapsed_ms", "accuracy", "validation_accuracy"},
+			{"1", "2", "0.5", "1", "", "2", "0.5", ""},
+			{"2", "4", "0.25", "0.5", "0.75", "3", "", ""},
+		}
+		if len(rows) != len(expected) {
+			t.Fatalf("expected %v, got %v", expected, rows)
+		}
+		for idx, row := range rows {
+			if strings.Join(row, ",") != strings.Join(expected[idx], ",") {
+				t.Errorf("row #%v; expected %v, got %v", idx, expected[idx], row)
+			}
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		nn := NewNeuralNet(nil, 2, 3, 2)
+		result, err := nn.Train(expectations, .5, RoundStrategy(2))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		buf := &bytes.Buffer{}
+		err = result.WriteJSON(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var decoded TrainResult
+		err = json.Unmarshal(buf.Bytes(), &decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.Reason != StopRounds || len(decoded.Epochs) != 2 || decoded.Epochs[1].Loss != result.Epochs[1].Loss {
+			t.Errorf("expected %+v, got %+v", result, decoded)
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package qndnn
+
+import (
+	"math"
+	"time"
+)
+
+// TrainingState describes the progress of training; strategies receive it
+// before the first epoch and after every epoch. Losses are measured by the
+// configured Loss, or squared error if there is none
+type TrainingState struct {
+	Epoch int `json:"epoch"` // completed epochs
+	Step  int `json:"step"`  // updates applied so far, one per batch
+
+	Loss       float64            `json:"loss"`              // mean loss per expectation over the last epoch
+	TotalLoss  float64            `json:"total_loss"`        // summed loss over all expectations of the last epoch
+	OutputLoss []float64          `json:"output_loss"`       // mean loss per output over the last epoch
+	Errors     []float64          `json:"errors"`            // mean absolute error per output over the last epoch, as reported by the loss
+	Metrics    map[string]float64 `json:"metrics,omitempty"` // mean of every metric over the last epoch; see WithMetric
+
+	Elapsed      time.Duration `json:"elapsed"`
+	LearningRate float64       `json:"learning_rate"`
+
+	Validated         bool               `json:"validated"`                    // if ValidationLoss is set; see WithValidation
+	ValidationLoss    float64            `json:"validation_loss"`              // mean loss per expectation of the validation set
+	ValidationMetrics map[string]float64 `json:"validation_metrics,omitempty"` // mean of every metric over the validation set
+
+	Start bool `json:"-"` // set on the first call of every training run; strategies keeping state reset it, so they can be reused
+
+	reason *StopReason // set by Stop, if the state was passed by Train
+}
+
+// Stop is to be returned by strategies to end training for the given reason,
+// which Train reports in TrainResult.Reason
+func (s TrainingState) Stop(reason StopReason) bool {
+	if s.reason != nil {
+		*s.reason = reason
+	}
+	return false
+}
+
+// ErrorStrategy adapts a strategy deciding on errors only, as strategies did
+// before TrainingState; it receives the errors of the state
+func ErrorStrategy(f func(errs []float64) bool) Strategy {
+	return func(s TrainingState) bool {
+		return f(s.Errors)
+	}
+}
+
+// epochLoss sums errors, losses and metrics of the expectations of an epoch
+type epochLoss struct {
+	errs    []float64
+	loss    []float64
+	metrics map[string]float64
+	n       int
+}
+
+func newEpochLoss(outputs int) *epochLoss {
+	return &epochLoss{
+		errs:    make([]float64, outputs),
+		loss:    make([]float64, outputs),
+		metrics: map[string]float64{},
+	}
+}
+
+// add records the errors reported by outputDeltas for out
+func (e *epochLoss) add(c TrainConfig, errs []float64, out []float64, expected []float64) {
+	for idx, err := range errs {
+		e.errs[idx] += math.Abs(err)
+		if c.Loss != nil {
+			e.loss[idx] += err // already the value of the loss
+		} else {
+			e.loss[idx] += squaredError{}.Value(out[idx], expected[idx])
+		}
+	}
+	for name, m := range c.Metrics {
+		e.metrics[name] += m(out, expected)
+	}
+	e.n++
+}
+
+// update sets the losses of the state to the means of the epoch and starts
+// over
+func (e *epochLoss) update(s *TrainingState) {
+	if e.n == 0 {
+		return
+	}
+
+	s.Errors = make([]float64, len(e.errs))
+	s.OutputLoss = make([]float64, len(e.loss))
+	s.TotalLoss = 0
+	for idx := range e.loss {
+		s.Errors[idx] = e.errs[idx] / float64(e.n)
+		s.OutputLoss[idx] = e.loss[idx] / float64(e.n)
+		s.TotalLoss += e.loss[idx]
+	}
+	s.Loss = s.TotalLoss / float64(e.n)
+
+	s.Metrics = nil
+	if len(e.metrics) > 0 {
+		s.Metrics = make(map[string]float64, len(e.metrics))
+		for name, v := range e.metrics {
+			s.Metrics[name] = v / float64(e.n)
+		}
+	}
+
+	clear(e.errs)
+	clear(e.loss)
+	clear(e.metrics)
+	e.n = 0
+}
+
+// evaluate returns the mean loss and metrics per expectation, e.g. of a
+// validation set
+func (d Dense) evaluate(p *pass, c TrainConfig, expectations []Expectations) (float64, map[string]float64) {
+	l := c.Loss
+	if l == nil {
+		l = SquaredError()
+	}
+
+	var metrics map[string]float64
+	if len(c.Metrics) > 0 {
+		metrics = make(map[string]float64, len(c.Metrics))
+	}
+
+	last := len(d) - 1
+	total := 0.0
+	for _, e := range expectations {
+		d.forward(p, e.Input)
+		for idx, out := range p.act[last] {
+			total += l.Value(out, e.Output[idx])
+		}
+		for name, m := range c.Metrics {
+			metrics[name] += m(p.act[last], e.Output)
+		}
+	}
+
+	for name := range metrics {
+		metrics[name] /= float64(len(expectations))
+	}
+	return total / float64(len(expectations)), metrics
+}
@@ -0,0 +1,160 @@
+package qndnn
+
+import (
+	"math"
+	"testing"
+)
+
+func Test_TrainingState(t *testing.T) {
+	expectations := []Expectations{
+		{Input: []float64{0, 1}, Output: []float64{1, 0}},
+		{Input: []float64{1, 0}, Output: []float64{0, 1}},
+		{Input: []float64{1, 1}, Output: []float64{1, 1}},
+	}
+
+	// record collects the states passed to the strategy for rounds epochs
+	record := func(states *[]TrainingState, rounds int) Strategy {
+		return func(s TrainingState) bool {
+			*states = append(*states, s)
+			return len(*states) <= rounds
+		}
+	}
+
+	t.Run("epoch loss", func(t *testing.T) {
+		nn := NewNeuralNet(nil, 2, 3, 2)
+		var states []TrainingState
+		_, err := nn.Train(expectations, .5, record(&states, 2))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(states) != 3 {
+			t.Fatalf("expected 3 strategy calls, got %v", len(states))
+		}
+		if s := states[0]; !s.Start || s.Epoch != 0 || s.Step != 0 || s.Errors != nil || s.Loss != 0 || s.LearningRate != .5 {
+			t.Errorf("unexpected initial state %+v", s)
+		}
+
+		for epoch, s := range states[1:] {
+			if s.Epoch != epoch+1 {
+				t.Errorf("expected epoch %v, got %v", epoch+1, s.Epoch)
+			}
+			if s.Start {
+				t.Errorf("expected start on the first call only, got it in epoch %v", s.Epoch)
+			}
+			if s.Step != (epoch+1)*len(expectations) {
+				t.Errorf("expected %v steps, got %v", (epoch+1)*len(expectations), s.Step)
+			}
+
+			total := 0.0
+			for _, l := range s.OutputLoss {
+				total += l
+			}
+			if math.Abs(total*float64(len(expectations))-s.TotalLoss) > 1e-12 {
+				t.Errorf("total loss %v doesn't match output losses %v", s.TotalLoss, s.OutputLoss)
+			}
+			if math.Abs(s.Loss-s.TotalLoss/float64(len(expectations))) > 1e-12 {
+				t.Errorf("loss %v isn't the mean of total loss %v", s.Loss, s.TotalLoss)
+			}
+			for idx, l := range s.OutputLoss {
+				// squared error is half the square of the absolute error, so the mean of
+				// it is at least half the square of the mean absolute error
+				if e := s.Errors[idx]; l < .5*e*e-1e-12 {
+					t.Errorf("output loss %v too small for error %v", l, e)
+				}
+			}
+			if s.Validated {
+				t.Error("expected no validation without validation set")
+			}
+		}
+
+		if states[2].Elapsed < states[1].Elapsed {
+			t.Error("expected elapsed time to increase")
+		}
+	})
+
+	t.Run("full batch", func(t *testing.T) {
+		nn := NewNeuralNet(nil, 2, 3, 2)
+		before, err := nn.Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var states []TrainingState
+		_, err = nn.Train(expectations, .5, record(&states, 1), WithBatchSize(len(expectations)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if states[1].Step != 1 {
+			t.Errorf("expected a single step, got %v", states[1].Step)
+		}
+
+		// with full batch the weights don't change within the epoch, so the loss is
+		// the one of the initial network
+		initial, err := NewNeuralNetFromSerialized(nil, before)
+		if err != nil {
+			t.Fatal(err)
+		}
+		total := 0.0
+		for _, e := range expectations {
+			out, err := initial.Output(e.Input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for idx, o := range out {
+				total += SquaredError().Value(o, e.Output[idx])
+			}
+		}
+		if math.Abs(total-states[1].TotalLoss) > 1e-12 {
+			t.Errorf("expected total loss %v, got %v", total, states[1].TotalLoss)
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		nn := NewNeuralNet(nil, 2, 3, 2)
+		validation := []Expectations{
+			{Input: []float64{0, 0}, Output: []float64{0, 0}},
+		}
+
+		var states []TrainingState
+		_, err := nn.Train(expectations, .5, record(&states, 1), WithValidation(validation), WithLoss(MeanAbsoluteError()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := nn.Output(validation[0].Input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := math.Abs(out[0]) + math.Abs(out[1])
+		if s := states[1]; !s.Validated || math.Abs(s.ValidationLoss-expected) > 1e-12 {
+			t.Errorf("expected validation loss %v, got %+v", expected, s)
+		}
+
+		_, err = nn.Train(expectations, .5, RoundStrategy(1), WithValidation([]Expectations{{Input: []float64{1}, Output: []float64{1, 1}}}))
+		if err == nil {
+			t.Error("expected error for mismatching validation set")
+		}
+	})
+
+	t.Run("error strategy", func(t *testing.T) {
+		nn := NewNeuralNet(nil, 2, 3, 2)
+		var reported [][]float64
+		_, err := nn.Train(expectations, .5, ErrorStrategy(func(errs []float64) bool {
+			reported = append(reported, errs)
+			return len(reported) < 2
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if reported[0] != nil || len(reported[1]) != 2 {
+			t.Errorf("unexpected errors %v", reported)
+		}
+		for _, err := range reported[1] {
+			if err < 0 {
+				t.Errorf("expected absolute errors, got %v", reported[1])
+			}
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package qndnn
+
+import (
+	"math"
+	"time"
+)
+
+// ask passes the state to f with a reason of its own, so combinators decide
+// which reason to report
+func ask(f Strategy, s TrainingState) (bool, StopReason) {
+	reason := StopStrategy
+	s.reason = &reason
+	return f(s), reason
+}
+
+// AnyOf stops as soon as one of the strategies stops, for the reason of the
+// first one stopping; all strategies see every epoch
+func AnyOf(strategies ...Strategy) Strategy {
+	return func(s TrainingState) bool {
+		next := true
+		var reason StopReason
+		for _, f := range strategies {
+			ok, r := ask(f, s)
+			if !ok && next {
+				next = false
+				reason = r
+			}
+		}
+
+		if next {
+			return true
+		}
+		return s.Stop(reason)
+	}
+}
+
+// AllOf stops once all strategies stop at the same epoch, for the reason of the
+// first one; all strategies see every epoch
+func AllOf(strategies ...Strategy) Strategy {
+	return func(s TrainingState) bool {
+		next := false
+		var reason StopReason
+		for idx, f := range strategies {
+			ok, r := ask(f, s)
+			next = next || ok
+			if idx == 0 {
+				reason = r
+			}
+		}
+
+		if next || len(strategies) == 0 {
+			return true
+		}
+		return s.Stop(reason)
+	}
+}
+
+// MaxDuration stops once training took at least d
+func MaxDuration(d time.Duration) Strategy {
+	return func(s TrainingState) bool {
+		if s.Elapsed >= d {
+			return s.Stop(StopTimeout)
+		}
+		return true
+	}
+}
+
+// MaxEpochs stops once n epochs are completed
+func MaxEpochs(n int) Strategy {
+	return func(s TrainingState) bool {
+		if s.Epoch >= n {
+			return s.Stop(StopRounds)
+		}
+		return true
+	}
+}
+
+// Patience stops once the validation loss (the training loss without
+// validation set) didn't improve for n epochs; see EarlyStopping
+func Patience(n int) Strategy {
+	return EarlyStopping(n, 0)
+}
+
+// Plateau stops once the training loss changed by no more than minDelta for n
+// epochs in a row, i.e. training doesn't make progress anymore
+func Plateau(minDelta float64, n int) Strategy {
+	previous := math.NaN()
+	flat := 0
+	return func(s TrainingState) bool {
+		if s.Start {
+			previous = math.NaN()
+			flat = 0
+		}
+
+		if s.Epoch == 0 {
+			return true // nothing measured yet
+		}
+
+		if math.Abs(s.Loss-previous) <= minDelta {
+			flat++
+		} else {
+			flat = 0
+		}
+		previous = s.Loss
+
+		if flat >= n {
+			return s.Stop(StopPlateau)
+		}
+		return true
+	}
+}
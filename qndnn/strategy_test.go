@@ -0,0 +1,176 @@
+package qndnn
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// epochs runs the strategy like Train does and returns the number of epochs
+// trained, with loss(epoch) as training loss, and the reason to stop
+func epochs(s Strategy, loss func(epoch int) float64) (int, StopReason) {
+	n := 0
+	for {
+		reason := StopStrategy
+		state := TrainingState{Epoch: n, Elapsed: time.Duration(n) * time.Second, reason: &reason, Start: n == 0}
+		if n > 0 {
+			state.Loss = loss(n)
+		}
+		if !s(state) {
+			return n, reason
+		}
+		n++
+	}
+}
+
+func constant(v float64) func(int) float64 {
+	return func(int) float64 {
+		return v
+	}
+}
+
+func Test_MaxEpochs(t *testing.T) {
+	n, reason := epochs(MaxEpochs(5), constant(1))
+	if n != 5 || reason != StopRounds {
+		t.Errorf("expected 5 epochs for '%v', got %v for '%v'", StopRounds, n, reason)
+	}
+
+	n, _ = epochs(MaxEpochs(0), constant(1))
+	if n != 0 {
+		t.Errorf("expected no epoch, got %v", n)
+	}
+}
+
+func Test_MaxDuration(t *testing.T) {
+	n, reason := epochs(MaxDuration(3*time.Second), constant(1))
+	if n != 3 || reason != StopTimeout {
+		t.Errorf("expected 3 epochs for '%v', got %v for '%v'", StopTimeout, n, reason)
+	}
+}
+
+func Test_Patience(t *testing.T) {
+	losses := []float64{0, 5, 4, 4.5, 3, 3.5, 3.2, 3.1, 2}
+	n, reason := epochs(Patience(3), func(epoch int) float64 {
+		return losses[epoch]
+	})
+
+	// best is 3 at epoch 4, epochs 5 to 7 don't improve on it
+	if n != 7 || reason != StopEarly {
+		t.Errorf("expected 7 epochs for '%v', got %v for '%v'", StopEarly, n, reason)
+	}
+}
+
+func Test_Plateau(t *testing.T) {
+	losses := []float64{0, 1, .5, .49, .3, .299, .2985, .298}
+	n, reason := epochs(Plateau(.02, 2), func(epoch int) float64 {
+		return losses[epoch]
+	})
+
+	// .49 is flat, but .3 breaks it; .299 and .2985 are flat again
+	if n != 6 || reason != StopPlateau {
+		t.Errorf("expected 6 epochs for '%v', got %v for '%v'", StopPlateau, n, reason)
+	}
+}
+
+func Test_AnyOf(t *testing.T) {
+	n, reason := epochs(AnyOf(MaxEpochs(10), MaxDuration(4*time.Second), RoundStrategy(6)), constant(1))
+	if n != 4 || reason != StopTimeout {
+		t.Errorf("expected 4 epochs for '%v', got %v for '%v'", StopTimeout, n, reason)
+	}
+
+	// all strategies see every epoch, so the counting one is in step
+	n, reason = epochs(AnyOf(MaxEpochs(10), RoundStrategy(6)), constant(1))
+	if n != 6 || reason != StopRounds {
+		t.Errorf("expected 6 epochs for '%v', got %v for '%v'", StopRounds, n, reason)
+	}
+
+	n, reason = epochs(AnyOf(MaxEpochs(2), MaxDuration(2*time.Second)), constant(1))
+	if n != 2 || reason != StopRounds {
+		t.Errorf("expected reason of first strategy '%v', got %v for '%v'", StopRounds, n, reason)
+	}
+}
+
+func Test_AllOf(t *testing.T) {
+	n, reason := epochs(AllOf(MaxEpochs(3), MaxDuration(5*time.Second)), constant(1))
+	if n != 5 || reason != StopRounds {
+		t.Errorf("expected 5 epochs for '%v', got %v for '%v'", StopRounds, n, reason)
+	}
+
+	// nested with AnyOf, the duration caps a plateau that never comes
+	n, reason = epochs(AnyOf(AllOf(MaxEpochs(2), Plateau(0, 1)), MaxDuration(6*time.Second)), func(epoch int) float64 {
+		return float64(10 - epoch)
+	})
+	if n != 6 || reason != StopTimeout {
+		t.Errorf("expected 6 epochs for '%v', got %v for '%v'", StopTimeout, n, reason)
+	}
+}
+
+func Test_StrategyReuse(t *testing.T) {
+	tcs := []struct {
+		name     string
+		strategy Strategy
+		expected int
+	}{
+		{"round", RoundStrategy(3), 3},
+		{"max epochs", MaxEpochs(3), 3},
+		{"patience", Patience(2), 3},
+		{"plateau", Plateau(0, 2), 3},
+		{"combined", AnyOf(RoundStrategy(4), AllOf(Patience(1), MaxEpochs(2))), 2},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			first, _ := epochs(tc.strategy, constant(1))
+			second, _ := epochs(tc.strategy, constant(1))
+			if first != second {
+				t.Errorf("expected the same number of epochs on reuse; first %v, second %v", first, second)
+			}
+			if first != tc.expected {
+				t.Errorf("expected %v epochs, got %v", tc.expected, first)
+			}
+		})
+	}
+
+	t.Run("train", func(t *testing.T) {
+		expectations := []Expectations{{Input: []float64{1}, Output: []float64{1}}}
+		s := AnyOf(RoundStrategy(3), ThresholdStrategy(0, time.Hour))
+		nn := NewNeuralNet(nil, 1, 2, 1)
+		for range 2 {
+			result, err := nn.Train(expectations, .5, s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Epochs) != 3 || result.Reason != StopRounds {
+				t.Errorf("expected 3 epochs for '%v', got %v for '%v'", StopRounds, len(result.Epochs), result.Reason)
+			}
+		}
+	})
+
+	t.Run("after cancel", func(t *testing.T) {
+		expectations := []Expectations{
+			{Input: []float64{1}, Output: []float64{1}},
+			{Input: []float64{0}, Output: []float64{0}},
+		}
+		s := RoundStrategy(3)
+		nn := NewNeuralNet(nil, 1, 2, 1)
+
+		// cancelled within the first epoch, so the strategy never sees epoch 1
+		ctx, cancel := context.WithCancel(context.Background())
+		result, err := nn.TrainContext(ctx, expectations, .5, s, WithMetric("cancel", func([]float64, []float64) float64 {
+			cancel()
+			return 0
+		}))
+		if !errors.Is(err, context.Canceled) || len(result.Epochs) != 0 {
+			t.Fatalf("expected cancel in first epoch, got %v epochs and '%v'", len(result.Epochs), err)
+		}
+
+		result, err = nn.Train(expectations, .5, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Epochs) != 3 || result.Reason != StopRounds {
+			t.Errorf("expected 3 epochs for '%v', got %v for '%v'", StopRounds, len(result.Epochs), result.Reason)
+		}
+	})
+}